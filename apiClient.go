@@ -2,49 +2,117 @@ package util
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"sync/atomic"
 	"time"
 )
 
+//JSONClient is set of APIClient request methods.
+//Code which depends on JSONClient instead of *APIClient
+//can be unit tested with utiltest.FakeClient.
+//Request and SubscribeSSE are not included, they return
+//concrete types bound to real connections of APIClient.
+type JSONClient interface {
+	GetJSON(url string, resp interface{}) error
+	GetJSONContext(ctx context.Context, url string, resp interface{}) error
+	PostJSON(url string, reqBody, resp interface{}) error
+	PostJSONContext(ctx context.Context, url string, reqBody, resp interface{}) error
+
+	Batch(ctx context.Context, reqs []BatchRequest, opts BatchOptions) []error
+	GetJSONBatch(ctx context.Context, urls []string, newResp func() interface{}, opts BatchOptions) []BatchResult
+	LoginSession(ctx context.Context, loginURL string, credentials, resp interface{}) error
+}
+
+var _ JSONClient = (*APIClient)(nil)
+
 //APIClient represents HTTP API client
 //APIClient reuse http client connections
 type APIClient struct {
 	client *http.Client
 
-	//http headers for every request
+	//transport of client, kept for connection level options
+	transport *http.Transport
+
+	//http headers for every request, map[string]string
 	//By default contains only Content-Type : application/json
-	headers map[string]string
+	//Stored map is never modified, WithHeaders replaces it
+	headers atomic.Value
 
 	//func for detecting success response code status
 	// if status success, client try to deserialize response body
 	// if status not success, client return error with response status code
 	//by default success status code only 200.
 	isSuccessStatus func(statusCode int) bool
+
+	//optional request metrics collector, nil when disabled
+	metrics *APIMetrics
+
+	//optional exporter of client spans, nil when tracing disabled
+	spanExporter SpanExporter
+
+	//optional request and response dumper, nil when debug disabled
+	debug *debugDumper
+
+	//max response body size in bytes, zero means unlimited
+	maxResponseSize int64
+
+	//response body decoding options
+	decodeOptions JSONDecodeOptions
+
+	//call Validate of decoded responses which implement Validator
+	validateResponses bool
+
+	//retry policy, zero value disables retries
+	retryPolicy RetryPolicy
+
+	//idempotency key generator for unsafe requests, nil when disabled
+	newIdempotencyKey func() string
+
+	//optional request rate limiter, nil when rate is unlimited
+	rateLimiter *rateLimiter
+
+	//base url for relative request urls, nil when not set
+	baseURL *url.URL
 }
 
 // NewAPIClient create new http client with request timeout
 func NewAPIClient(timeoutMs int) *APIClient {
-	return &APIClient{
-		client: newHTTPClient(timeoutMs),
-		headers: map[string]string{
-			"Content-Type": "application/json",
-		},
+	client := newHTTPClient(timeoutMs)
+	c := &APIClient{
+		client:    client,
+		transport: client.Transport.(*http.Transport),
 		isSuccessStatus: func(statusCode int) bool {
 			return statusCode == http.StatusOK
 		},
 	}
+	c.headers.Store(map[string]string{
+		"Content-Type": "application/json",
+	})
+	return c
 }
 
-// WithHeaders setup api client default headers
+// WithHeaders setup api client default headers.
+// Headers map is copied and replaced atomically, so it is safe to call
+// while requests are sent. Use Request builder for per-call headers.
 func (c *APIClient) WithHeaders(headers map[string]string) *APIClient {
-	c.headers = headers
+	copied := make(map[string]string, len(headers))
+	for name, val := range headers {
+		copied[name] = val
+	}
+	c.headers.Store(copied)
 	return c
 }
 
+//defaultHeaders return current default headers, map must not be modified
+func (c *APIClient) defaultHeaders() map[string]string {
+	return c.headers.Load().(map[string]string)
+}
+
 // WithSuccessStatus setup success status detection func
 func (c *APIClient) WithSuccessStatus(
 	isSuccessStatus func(statusCode int) bool) *APIClient {
@@ -52,6 +120,79 @@ func (c *APIClient) WithSuccessStatus(
 	return c
 }
 
+// WithMetrics setup metrics collector for every client request.
+// One collector can be shared between several clients.
+func (c *APIClient) WithMetrics(metrics *APIMetrics) *APIClient {
+	c.metrics = metrics
+	return c
+}
+
+// WithTracing enable client span creation for every request.
+// Finished spans are passed to exporter, trace context from request context
+// is propagated to server by W3C traceparent and tracestate headers.
+func (c *APIClient) WithTracing(exporter SpanExporter) *APIClient {
+	c.spanExporter = exporter
+	return c
+}
+
+// WithDebug enable logging of full request and response dumps.
+// Secret headers and configured JSON fields are redacted.
+func (c *APIClient) WithDebug(opts DebugOptions) *APIClient {
+	c.debug = newDebugDumper(opts)
+	return c
+}
+
+// WithMaxResponseSize limit response body size.
+// Larger response fails with *ResponseTooLargeError, zero disables limit.
+func (c *APIClient) WithMaxResponseSize(maxBytes int64) *APIClient {
+	c.maxResponseSize = maxBytes
+	return c
+}
+
+// WithJSONDecodeOptions setup strict response body decoding
+func (c *APIClient) WithJSONDecodeOptions(opts JSONDecodeOptions) *APIClient {
+	c.decodeOptions = opts
+	return c
+}
+
+// WithRetry setup retry policy for failed requests.
+// POST and PATCH requests are retried only with Idempotency-Key header.
+func (c *APIClient) WithRetry(policy RetryPolicy) *APIClient {
+	c.retryPolicy = policy
+	return c
+}
+
+// WithIdempotencyKeys enable Idempotency-Key header for POST and PATCH
+// requests. One key is generated per call and reused by all its retries.
+// When generate is nil NewIdempotencyKey is used.
+func (c *APIClient) WithIdempotencyKeys(generate func() string) *APIClient {
+	if generate == nil {
+		generate = NewIdempotencyKey
+	}
+	c.newIdempotencyKey = generate
+	return c
+}
+
+// WithBaseURL setup base url for relative request urls,
+// e.g. "/v1/items" with base "https://api.example.com/" is sent
+// to "https://api.example.com/v1/items". Absolute urls are not changed.
+func (c *APIClient) WithBaseURL(baseURL *url.URL) *APIClient {
+	c.baseURL = baseURL
+	return c
+}
+
+//resolveURL return request url resolved against client base url
+func (c *APIClient) resolveURL(rawurl string) string {
+	if c.baseURL == nil {
+		return rawurl
+	}
+	ref, err := url.Parse(rawurl)
+	if err != nil || ref.IsAbs() {
+		return rawurl
+	}
+	return c.baseURL.ResolveReference(ref).String()
+}
+
 //newHTTPClient creates new http client with timeout
 func newHTTPClient(timeoutMs int) *http.Client {
 	transport := &http.Transport{
@@ -66,57 +207,127 @@ func newHTTPClient(timeoutMs int) *http.Client {
 // GetJSON send get http request to url with given req.
 // Stores the result  in the value pointed to by res
 func (c *APIClient) GetJSON(url string, resp interface{}) error {
+	return c.GetJSONContext(context.Background(), url, resp)
+}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return err
-	}
-	for name, val := range c.headers {
-		req.Header.Set(name, val)
-	}
-	res, err := c.client.Do(req)
+// GetJSONContext send get http request to url within given context.
+// Stores the result  in the value pointed to by resp
+func (c *APIClient) GetJSONContext(ctx context.Context, url string, resp interface{}) error {
+
+	req, err := newJSONRequest(ctx, http.MethodGet, c.resolveURL(url), nil)
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
-	if !c.isSuccessStatus(res.StatusCode) {
-		io.Copy(ioutil.Discard, res.Body)
-		return fmt.Errorf("response status code %d, url %s", res.StatusCode, url)
-	}
 
-	return json.NewDecoder(res.Body).Decode(resp)
+	return c.doJSON(req, resp)
 }
 
 // PostJSON send post http request to url with given req.
-// Stores the result  in the value pointed to by resp
+// Stores the result  in the value pointed to by resp,
+// response body is ignored when resp is nil
 func (c *APIClient) PostJSON(url string, reqBody, resp interface{}) error {
+	return c.PostJSONContext(context.Background(), url, reqBody, resp)
+}
+
+// PostJSONContext send post http request to url within given context.
+// Stores the result  in the value pointed to by resp
+func (c *APIClient) PostJSONContext(ctx context.Context, url string, reqBody, resp interface{}) error {
 
-	buf := new(bytes.Buffer)
-	if err := json.NewEncoder(buf).Encode(reqBody); err != nil {
+	req, err := newJSONRequest(ctx, http.MethodPost, c.resolveURL(url), reqBody)
+	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, buf)
+	return c.doJSON(req, resp)
+}
+
+//newJSONRequest creates request with JSON encoded body,
+//GET and HEAD requests have no body
+func newJSONRequest(ctx context.Context, method, url string, reqBody interface{}) (*http.Request, error) {
+	var body io.Reader
+	if method != http.MethodGet && method != http.MethodHead {
+		buf := new(bytes.Buffer)
+		if err := json.NewEncoder(buf).Encode(reqBody); err != nil {
+			return nil, err
+		}
+		body = buf
+	}
+
+	req, err := http.NewRequest(method, url, body)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return req.WithContext(ctx), nil
+}
+
+//doJSON sends prepared request with client default headers
+//and decodes success response body into resp.
+//Headers already set in request take precedence over default ones.
+//Failed attempts are repeated according to client retry policy.
+func (c *APIClient) doJSON(req *http.Request, resp interface{}) error {
+	for name, val := range c.defaultHeaders() {
+		if _, ok := req.Header[http.CanonicalHeaderKey(name)]; !ok {
+			req.Header.Set(name, val)
+		}
 	}
+	c.setIdempotencyKey(req)
+
+	for attempt := 1; ; attempt++ {
+		err := c.attemptJSON(req, resp)
+		if err == nil || !c.retryPolicy.retryable(req, err, attempt) {
+			return err
+		}
+		if waitErr := c.retryPolicy.wait(req.Context(), attempt); waitErr != nil {
+			return err
+		}
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return err
+			}
+			req.Body = body
+		}
+	}
+}
 
-	for name, val := range c.headers {
-		req.Header.Set(name, val)
+//attemptJSON sends request once and decodes success response body into resp
+func (c *APIClient) attemptJSON(req *http.Request, resp interface{}) (err error) {
+	if err := c.rateLimiter.wait(req.Context()); err != nil {
+		return err
 	}
 
-	res, err := c.client.Do(req)
+	endSpan := startClientSpan(c.spanExporter, req)
+	endMetrics := c.metrics.begin(req)
+	var res *http.Response
+	defer func() {
+		endMetrics(res, err)
+		endSpan(res, err)
+	}()
+
+	c.debug.dumpRequest(req)
+	start := time.Now()
+	res, err = c.client.Do(req)
 	if err != nil {
+		c.debug.dumpError(req, err, start)
 		return err
 	}
+	c.debug.dumpResponse(req, res, start)
 	defer res.Body.Close()
+	recordRedirectChain(req, res)
+	recordSetCookies(req, res)
 
 	if !c.isSuccessStatus(res.StatusCode) {
+		c.captureErrorBody(req, res)
 		//for reuse http client connection
 		io.Copy(ioutil.Discard, res.Body)
 
-		return fmt.Errorf("response status code %d, url %s", res.StatusCode, url)
+		return &StatusError{StatusCode: res.StatusCode, URL: req.URL.String()}
+	}
+
+	if resp == nil {
+		io.Copy(ioutil.Discard, res.Body)
+		return nil
 	}
 
-	return json.NewDecoder(res.Body).Decode(resp)
+	return c.decodeJSON(req, res, resp)
 }
@@ -0,0 +1,138 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+//DefaultBatchParallelism number of concurrent requests of batch by default
+const DefaultBatchParallelism = 8
+
+//BatchOptions configure batch execution
+type BatchOptions struct {
+	//Parallelism max number of concurrent requests,
+	//DefaultBatchParallelism when zero
+	Parallelism int
+
+	//FailFast cancel not finished requests after first error,
+	//both in-flight and not started ones get context.Canceled error
+	FailFast bool
+
+	//Timeout deadline for whole batch, unlimited when zero.
+	//Requests not finished in time, in-flight or not started,
+	//get context.DeadlineExceeded error.
+	Timeout time.Duration
+}
+
+//BatchRequest is single request of batch.
+//Request is POST with JSON encoded Body when Body is not nil, GET otherwise.
+type BatchRequest struct {
+	URL  string
+	Body interface{}
+
+	//Resp destination for response, may be nil for POST
+	Resp interface{}
+}
+
+//BatchResult is result of single GetJSONBatch request
+type BatchResult struct {
+	URL  string
+	Resp interface{}
+	Err  error
+}
+
+//Batch executes requests with bounded parallelism.
+//Return errors in order of requests, nil for successful request.
+//Failed request does not abort others unless FailFast is set.
+func (c *APIClient) Batch(ctx context.Context, reqs []BatchRequest, opts BatchOptions) []error {
+	errs := make([]error, len(reqs))
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultBatchParallelism
+	}
+	if parallelism > len(reqs) {
+		parallelism = len(reqs)
+	}
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(parallelism)
+	for w := 0; w < parallelism; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = c.doBatchRequest(ctx, reqs[i])
+				if errs[i] != nil && opts.FailFast {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for i := range reqs {
+		if err := ctx.Err(); err != nil {
+			//requests which are not started get context error
+			for ; i < len(reqs); i++ {
+				errs[i] = err
+			}
+			break
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return errs
+}
+
+func (c *APIClient) doBatchRequest(ctx context.Context, req BatchRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var err error
+	if req.Body != nil {
+		err = c.PostJSONContext(ctx, req.URL, req.Body, req.Resp)
+	} else {
+		err = c.GetJSONContext(ctx, req.URL, req.Resp)
+	}
+	//in-flight request aborted by batch gets bare context error,
+	//errors of requests finished before abort are kept
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil && errors.Is(err, ctxErr) {
+		return ctxErr
+	}
+	return err
+}
+
+//GetJSONBatch send get requests to urls with bounded parallelism.
+//Response of every url is decoded into new value created by newResp.
+//Results have the same order as urls.
+func (c *APIClient) GetJSONBatch(ctx context.Context, urls []string,
+	newResp func() interface{}, opts BatchOptions) []BatchResult {
+	reqs := make([]BatchRequest, len(urls))
+	for i, url := range urls {
+		reqs[i] = BatchRequest{URL: url, Resp: newResp()}
+	}
+
+	errs := c.Batch(ctx, reqs, opts)
+
+	results := make([]BatchResult, len(urls))
+	for i, req := range reqs {
+		results[i] = BatchResult{URL: req.URL, Resp: req.Resp, Err: errs[i]}
+	}
+	return results
+}
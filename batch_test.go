@@ -0,0 +1,162 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBatchTestServer(inFlight, maxInFlight *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+		for {
+			max := atomic.LoadInt32(maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(maxInFlight, max, n) {
+				break
+			}
+		}
+
+		id := strings.TrimPrefix(r.URL.Path, "/")
+		switch {
+		case id == "fail":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case id == "slow":
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		default:
+			time.Sleep(5 * time.Millisecond)
+		}
+		w.Write([]byte(`{"id":` + id + `}`))
+	}))
+}
+
+func TestAPIClientGetJSONBatch(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := newBatchTestServer(&inFlight, &maxInFlight)
+	defer server.Close()
+
+	urls := make([]string, 20)
+	for i := range urls {
+		urls[i] = server.URL + "/" + strconv.Itoa(i)
+	}
+	urls[5] = server.URL + "/fail"
+
+	results := NewAPIClient(1000).GetJSONBatch(context.Background(), urls,
+		func() interface{} { return &TestPost{} },
+		BatchOptions{Parallelism: 3})
+
+	require.Len(t, results, len(urls))
+	for i, result := range results {
+		assert.Equal(t, urls[i], result.URL)
+		if i == 5 {
+			assert.NotNil(t, result.Err)
+			continue
+		}
+		require.Nil(t, result.Err)
+		assert.Equal(t, i, result.Resp.(*TestPost).ID)
+	}
+	assert.True(t, atomic.LoadInt32(&maxInFlight) <= 3)
+}
+
+func TestAPIClientBatchFailFast(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := newBatchTestServer(&inFlight, &maxInFlight)
+	defer server.Close()
+
+	reqs := []BatchRequest{
+		{URL: server.URL + "/slow", Resp: &TestPost{}},
+		{URL: server.URL + "/fail", Resp: &TestPost{}},
+		{URL: server.URL + "/3", Resp: &TestPost{}},
+		{URL: server.URL + "/4", Resp: &TestPost{}},
+	}
+
+	start := time.Now()
+	errs := NewAPIClient(2000).Batch(context.Background(), reqs,
+		BatchOptions{Parallelism: 2, FailFast: true})
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+
+	for _, err := range errs {
+		assert.NotNil(t, err)
+	}
+	_, ok := errs[1].(*StatusError)
+	assert.True(t, ok)
+	//in-flight request is cancelled
+	assert.Equal(t, context.Canceled, errs[0])
+}
+
+//statusTransport closes received when response of url path is received
+type statusTransport struct {
+	next     http.RoundTripper
+	path     string
+	received chan struct{}
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err == nil && req.URL.Path == t.path {
+		close(t.received)
+	}
+	return res, err
+}
+
+func TestAPIClientBatchFailFastKeepsStatusError(t *testing.T) {
+	received := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			<-received
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		//status is received before cancel, body is aborted by it
+		w.WriteHeader(http.StatusNotFound)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(2000)
+	client.client.Transport = &statusTransport{next: client.transport, path: "/missing", received: received}
+	reqs := []BatchRequest{
+		{URL: server.URL + "/missing", Resp: &TestPost{}},
+		{URL: server.URL + "/fail", Resp: &TestPost{}},
+	}
+	errs := client.Batch(context.Background(), reqs, BatchOptions{Parallelism: 2, FailFast: true})
+
+	statusErr, ok := errs[0].(*StatusError)
+	require.True(t, ok, "%v", errs[0])
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	statusErr, ok = errs[1].(*StatusError)
+	require.True(t, ok, "%v", errs[1])
+	assert.Equal(t, http.StatusInternalServerError, statusErr.StatusCode)
+}
+
+func TestAPIClientBatchTimeout(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := newBatchTestServer(&inFlight, &maxInFlight)
+	defer server.Close()
+
+	reqs := []BatchRequest{
+		{URL: server.URL + "/1", Resp: &TestPost{}},
+		{URL: server.URL + "/slow", Resp: &TestPost{}},
+		{URL: server.URL + "/3", Resp: &TestPost{}},
+	}
+
+	errs := NewAPIClient(2000).Batch(context.Background(), reqs,
+		BatchOptions{Parallelism: 1, Timeout: 100 * time.Millisecond})
+	assert.Nil(t, errs[0])
+	//in-flight request
+	assert.Equal(t, context.DeadlineExceeded, errs[1])
+	assert.Equal(t, context.DeadlineExceeded, errs[2])
+}
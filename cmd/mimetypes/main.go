@@ -0,0 +1,57 @@
+//Command mimetypes prints effective MIME table of util package
+//in mime.types format. Built-in types are merged with system
+//mime.types files and files given as arguments:
+//
+//	mimetypes [-system] [-override] [file ...]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/crazyslon/util"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+//run execute command with args and return exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("mimetypes", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	system := flags.Bool("system", false, "load system mime.types files")
+	override := flags.Bool("override", false, "types from files replace built-in types")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	registry := util.DefaultMimeRegistry.Clone()
+	if *system {
+		if err := registry.LoadSystemMimeTypes(); err != nil {
+			return fail(stderr, err)
+		}
+	}
+
+	priority := util.MimePreferExisting
+	if *override {
+		priority = util.MimePreferLoaded
+	}
+	for _, path := range flags.Args() {
+		if err := registry.LoadMimeTypesFile(path, priority); err != nil {
+			return fail(stderr, err)
+		}
+	}
+
+	if err := registry.WriteMimeTypes(stdout); err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
+
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintln(stderr, err)
+	return 1
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//mimeLine return output line of mime type, empty when not found
+func mimeLine(output, mime string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, mime+"\t") {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mimetypes")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "mime.types")
+	require.Nil(t, ioutil.WriteFile(path, []byte("video/x-custom\tmp4 cst\n"), 0644))
+
+	//built-in types are kept by default, only new extensions are added
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{path}, &stdout, &stderr))
+	assert.Empty(t, stderr.String())
+	assert.True(t, strings.HasPrefix(mimeLine(stdout.String(), "video/mp4"), "video/mp4\tmp4 "))
+	assert.Equal(t, "video/x-custom\tcst", mimeLine(stdout.String(), "video/x-custom"))
+
+	//with -override file types replace built-in ones
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"-override", path}, &stdout, &stderr))
+	assert.Empty(t, stderr.String())
+	assert.NotContains(t, mimeLine(stdout.String(), "video/mp4"), "\tmp4 ")
+	assert.Equal(t, "video/x-custom\tmp4 cst", mimeLine(stdout.String(), "video/x-custom"))
+}
+
+func TestRunErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mimetypes")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "mime.types")
+	require.Nil(t, ioutil.WriteFile(path, []byte("text/plain txt\ntypes\n"), 0644))
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, run([]string{path}, &stdout, &stderr))
+	assert.Equal(t, path+":2: missing { after types\n", stderr.String())
+	assert.Empty(t, stdout.String())
+
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"-unknown"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "flag provided but not defined: -unknown")
+}
@@ -0,0 +1,327 @@
+package util
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ClientConfig describes APIClient settings which can be loaded
+//from JSON file and environment variables.
+//Environment variable name is prefix, "_" and env tag of field,
+//nested fields join tags by "_", e.g. APICLIENT_RETRY_MAX_ATTEMPTS.
+//Headers are set by variables like APICLIENT_HEADER_X_API_KEY,
+//underscores of header name are replaced by dashes, X-Api-Key here.
+type ClientConfig struct {
+	//TimeoutMs overall request timeout, required
+	TimeoutMs int `json:"timeoutMs" env:"TIMEOUT_MS"`
+
+	//DialTimeoutMs connection timeout, unlimited when zero
+	DialTimeoutMs int `json:"dialTimeoutMs" env:"DIAL_TIMEOUT_MS"`
+
+	//IdleConnTimeoutMs how long idle connection is kept, forever when zero
+	IdleConnTimeoutMs int `json:"idleConnTimeoutMs" env:"IDLE_CONN_TIMEOUT_MS"`
+
+	//MaxIdleConnsPerHost max idle connections per host, client default when zero
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost" env:"MAX_IDLE_CONNS_PER_HOST"`
+
+	//MaxResponseBytes response body size limit, unlimited when zero
+	MaxResponseBytes int64 `json:"maxResponseBytes" env:"MAX_RESPONSE_BYTES"`
+
+	//BaseURL absolute url for relative request urls
+	BaseURL string `json:"baseUrl" env:"BASE_URL"`
+
+	//Headers default request headers, they are merged over
+	//client defaults (Content-Type: application/json)
+	Headers map[string]string `json:"headers"`
+
+	Retry     RetryConfig     `json:"retry" env:"RETRY"`
+	RateLimit RateLimitConfig `json:"rateLimit" env:"RATE_LIMIT"`
+	TLS       TLSConfig       `json:"tls" env:"TLS"`
+}
+
+//RetryConfig is configuration of RetryPolicy
+type RetryConfig struct {
+	MaxAttempts  int `json:"maxAttempts" env:"MAX_ATTEMPTS"`
+	BackoffMs    int `json:"backoffMs" env:"BACKOFF_MS"`
+	MaxBackoffMs int `json:"maxBackoffMs" env:"MAX_BACKOFF_MS"`
+}
+
+//RateLimitConfig is configuration of client request rate limit
+type RateLimitConfig struct {
+	//RequestsPerSecond max request rate, unlimited when zero
+	RequestsPerSecond float64 `json:"requestsPerSecond" env:"REQUESTS_PER_SECOND"`
+	Burst             int     `json:"burst" env:"BURST"`
+}
+
+//TLSConfig is configuration of client TLS connections
+type TLSConfig struct {
+	//CAFile PEM file with trusted root certificates, system roots when empty
+	CAFile string `json:"caFile" env:"CA_FILE"`
+
+	//CertFile and KeyFile PEM files of client certificate
+	CertFile string `json:"certFile" env:"CERT_FILE"`
+	KeyFile  string `json:"keyFile" env:"KEY_FILE"`
+
+	//ServerName overrides server name for certificate verification
+	ServerName string `json:"serverName" env:"SERVER_NAME"`
+
+	//MinVersion min TLS version: "1.0", "1.1", "1.2" or "1.3"
+	MinVersion string `json:"minVersion" env:"MIN_VERSION"`
+
+	InsecureSkipVerify bool `json:"insecureSkipVerify" env:"INSECURE_SKIP_VERIFY"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+//ConfigError returned when config can not be loaded or is invalid
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid client config: " + strings.Join(e.Problems, "; ")
+}
+
+//LoadClientConfig load config from JSON file at path, then override
+//values by environment variables with envPrefix and validate result.
+//File is skipped when path is empty, environment when envPrefix is empty.
+//Unknown fields of file are rejected, so typos are not silently ignored.
+func LoadClientConfig(path, envPrefix string) (*ClientConfig, error) {
+	cfg := &ClientConfig{}
+
+	if len(path) > 0 {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, &ConfigError{Problems: []string{fmt.Sprintf("%s: %v", path, err)}}
+		}
+	}
+
+	if len(envPrefix) > 0 {
+		if err := cfg.applyEnv(envPrefix, os.Environ()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+//applyEnv override config values by variables from environ
+func (cfg *ClientConfig) applyEnv(prefix string, environ []string) error {
+	env := make(map[string]string, len(environ))
+	headerPrefix := prefix + "_HEADER_"
+	for _, kv := range environ {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 || !strings.HasPrefix(kv, prefix+"_") {
+			continue
+		}
+		name, value := kv[:i], kv[i+1:]
+		if strings.HasPrefix(name, headerPrefix) {
+			if cfg.Headers == nil {
+				cfg.Headers = make(map[string]string)
+			}
+			header := http.CanonicalHeaderKey(strings.Replace(strings.TrimPrefix(name, headerPrefix), "_", "-", -1))
+			cfg.Headers[header] = value
+			continue
+		}
+		env[name] = value
+	}
+
+	var problems []string
+	setEnvFields(reflect.ValueOf(cfg).Elem(), prefix, env, &problems)
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+//setEnvFields set struct fields with env tag from env map
+func setEnvFields(v reflect.Value, prefix string, env map[string]string, problems *[]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("env")
+		if len(tag) == 0 {
+			continue
+		}
+		name := prefix + "_" + tag
+		field := v.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			setEnvFields(field, name, env, problems)
+			continue
+		}
+
+		value, ok := env[name]
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Int, reflect.Int64:
+			var n int64
+			n, err = strconv.ParseInt(value, 10, 64)
+			field.SetInt(n)
+		case reflect.Float64:
+			var f float64
+			f, err = strconv.ParseFloat(value, 64)
+			field.SetFloat(f)
+		case reflect.Bool:
+			var b bool
+			b, err = strconv.ParseBool(value)
+			field.SetBool(b)
+		}
+		if err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s: invalid value %q", name, value))
+		}
+	}
+}
+
+//Validate check config values and return *ConfigError with all problems
+func (cfg *ClientConfig) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(cfg.TimeoutMs > 0, "timeoutMs must be positive")
+	check(cfg.DialTimeoutMs >= 0, "dialTimeoutMs must not be negative")
+	check(cfg.IdleConnTimeoutMs >= 0, "idleConnTimeoutMs must not be negative")
+	check(cfg.MaxIdleConnsPerHost >= 0, "maxIdleConnsPerHost must not be negative")
+	check(cfg.MaxResponseBytes >= 0, "maxResponseBytes must not be negative")
+
+	if len(cfg.BaseURL) > 0 {
+		u, err := url.Parse(cfg.BaseURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && len(u.Host) > 0,
+			"baseUrl must be absolute http or https url")
+	}
+
+	check(cfg.Retry.MaxAttempts >= 0, "retry.maxAttempts must not be negative")
+	check(cfg.Retry.BackoffMs >= 0, "retry.backoffMs must not be negative")
+	check(cfg.Retry.MaxBackoffMs >= 0, "retry.maxBackoffMs must not be negative")
+
+	check(cfg.RateLimit.RequestsPerSecond >= 0, "rateLimit.requestsPerSecond must not be negative")
+	check(cfg.RateLimit.Burst >= 0, "rateLimit.burst must not be negative")
+
+	check((len(cfg.TLS.CertFile) > 0) == (len(cfg.TLS.KeyFile) > 0),
+		"tls.certFile and tls.keyFile must be set together")
+	if len(cfg.TLS.MinVersion) > 0 {
+		_, ok := tlsVersions[cfg.TLS.MinVersion]
+		check(ok, "tls.minVersion %q is not supported", cfg.TLS.MinVersion)
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+//tlsConfig build tls.Config, nil when defaults are enough
+func (cfg *TLSConfig) tlsConfig() (*tls.Config, error) {
+	if *cfg == (TLSConfig{}) {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		MinVersion:         tlsVersions[cfg.MinVersion],
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if len(cfg.CAFile) > 0 {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(cfg.CertFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+//NewAPIClientFromConfig validate config and create client with its settings
+func NewAPIClientFromConfig(cfg *ClientConfig) (*APIClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := cfg.TLS.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewAPIClient(cfg.TimeoutMs)
+	c.transport.TLSClientConfig = tlsConfig
+	if cfg.DialTimeoutMs > 0 {
+		dialer := &net.Dialer{Timeout: time.Duration(cfg.DialTimeoutMs) * time.Millisecond}
+		c.transport.DialContext = dialer.DialContext
+	}
+	c.transport.IdleConnTimeout = time.Duration(cfg.IdleConnTimeoutMs) * time.Millisecond
+	if cfg.MaxIdleConnsPerHost > 0 {
+		c.transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+
+	if len(cfg.Headers) > 0 {
+		headers := make(map[string]string, len(cfg.Headers)+1)
+		for name, val := range c.defaultHeaders() {
+			headers[http.CanonicalHeaderKey(name)] = val
+		}
+		for name, val := range cfg.Headers {
+			headers[http.CanonicalHeaderKey(name)] = val
+		}
+		c.WithHeaders(headers)
+	}
+	if len(cfg.BaseURL) > 0 {
+		//url is checked by Validate
+		baseURL, _ := url.Parse(cfg.BaseURL)
+		c.WithBaseURL(baseURL)
+	}
+
+	return c.
+		WithMaxResponseSize(cfg.MaxResponseBytes).
+		WithRetry(RetryPolicy{
+			MaxAttempts: cfg.Retry.MaxAttempts,
+			Backoff:     time.Duration(cfg.Retry.BackoffMs) * time.Millisecond,
+			MaxBackoff:  time.Duration(cfg.Retry.MaxBackoffMs) * time.Millisecond,
+		}).
+		WithRateLimit(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst), nil
+}
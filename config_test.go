@@ -0,0 +1,137 @@
+package util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestConfig(t *testing.T, data string) string {
+	dir, err := ioutil.TempDir("", "config")
+	require.Nil(t, err)
+	path := filepath.Join(dir, "client.json")
+	require.Nil(t, ioutil.WriteFile(path, []byte(data), 0600))
+	return path
+}
+
+func TestLoadClientConfig(t *testing.T) {
+	path := writeTestConfig(t, `{
+		"timeoutMs": 1000,
+		"baseUrl": "http://example.com/api/",
+		"headers": {"X-Tenant": "acme"},
+		"retry": {"maxAttempts": 2, "backoffMs": 10},
+		"rateLimit": {"requestsPerSecond": 5}
+	}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	os.Setenv("TESTCLIENT_TIMEOUT_MS", "2500")
+	os.Setenv("TESTCLIENT_RETRY_MAX_ATTEMPTS", "4")
+	os.Setenv("TESTCLIENT_TLS_INSECURE_SKIP_VERIFY", "true")
+	os.Setenv("TESTCLIENT_HEADER_X_API_KEY", "secret")
+	defer func() {
+		os.Unsetenv("TESTCLIENT_TIMEOUT_MS")
+		os.Unsetenv("TESTCLIENT_RETRY_MAX_ATTEMPTS")
+		os.Unsetenv("TESTCLIENT_TLS_INSECURE_SKIP_VERIFY")
+		os.Unsetenv("TESTCLIENT_HEADER_X_API_KEY")
+	}()
+
+	cfg, err := LoadClientConfig(path, "TESTCLIENT")
+	require.Nil(t, err)
+	assert.Equal(t, 2500, cfg.TimeoutMs)
+	assert.Equal(t, "http://example.com/api/", cfg.BaseURL)
+	assert.Equal(t, 4, cfg.Retry.MaxAttempts)
+	assert.Equal(t, 10, cfg.Retry.BackoffMs)
+	assert.Equal(t, 5.0, cfg.RateLimit.RequestsPerSecond)
+	assert.True(t, cfg.TLS.InsecureSkipVerify)
+	assert.Equal(t, map[string]string{"X-Tenant": "acme", "X-Api-Key": "secret"}, cfg.Headers)
+}
+
+func TestLoadClientConfigErrors(t *testing.T) {
+	_, err := LoadClientConfig("not-exists.json", "")
+	assert.NotNil(t, err)
+
+	path := writeTestConfig(t, `{"timeoutMs": "fast"}`)
+	defer os.RemoveAll(filepath.Dir(path))
+	_, err = LoadClientConfig(path, "")
+	assert.IsType(t, &ConfigError{}, err)
+
+	typo := writeTestConfig(t, `{"timeoutMs": 1000, "timout_ms": 5000}`)
+	defer os.RemoveAll(filepath.Dir(typo))
+	_, err = LoadClientConfig(typo, "")
+	require.IsType(t, &ConfigError{}, err)
+	assert.Contains(t, err.Error(), `unknown field "timout_ms"`)
+
+	os.Setenv("TESTCLIENT_DIAL_TIMEOUT_MS", "soon")
+	defer os.Unsetenv("TESTCLIENT_DIAL_TIMEOUT_MS")
+	_, err = LoadClientConfig("", "TESTCLIENT")
+	require.IsType(t, &ConfigError{}, err)
+	assert.Equal(t, []string{`TESTCLIENT_DIAL_TIMEOUT_MS: invalid value "soon"`}, err.(*ConfigError).Problems)
+}
+
+func TestClientConfigValidate(t *testing.T) {
+	cfg := &ClientConfig{
+		BaseURL:   "/relative",
+		RateLimit: RateLimitConfig{Burst: -1},
+		TLS:       TLSConfig{CertFile: "cert.pem", MinVersion: "1.4"},
+	}
+	err := cfg.Validate()
+	require.IsType(t, &ConfigError{}, err)
+	assert.Equal(t, []string{
+		"timeoutMs must be positive",
+		"baseUrl must be absolute http or https url",
+		"rateLimit.burst must not be negative",
+		"tls.certFile and tls.keyFile must be set together",
+		`tls.minVersion "1.4" is not supported`,
+	}, err.(*ConfigError).Problems)
+
+	assert.Nil(t, (&ClientConfig{TimeoutMs: 1000, TLS: TLSConfig{MinVersion: "1.2"}}).Validate())
+}
+
+func TestNewAPIClientFromConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"method":"` + r.Method + `","query":"` + r.URL.Path +
+			`","contentType":"` + r.Header.Get("Content-Type") +
+			`","tenant":"` + r.Header.Get("X-Tenant") + `"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewAPIClientFromConfig(&ClientConfig{
+		TimeoutMs:           1000,
+		DialTimeoutMs:       500,
+		MaxIdleConnsPerHost: 4,
+		BaseURL:             server.URL + "/api/",
+		Headers:             map[string]string{"X-Tenant": "acme"},
+		Retry:               RetryConfig{MaxAttempts: 3, BackoffMs: 10},
+		TLS:                 TLSConfig{MinVersion: "1.2"},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, time.Second, client.client.Timeout)
+	assert.Equal(t, 4, client.transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 3, client.retryPolicy.MaxAttempts)
+	require.NotNil(t, client.transport.TLSClientConfig)
+
+	echo := &testEcho{}
+	require.Nil(t, client.GetJSON("items", echo))
+	assert.Equal(t, "/api/items", echo.Query)
+	assert.Equal(t, "acme", echo.Tenant)
+
+	//configured headers do not drop default Content-Type
+	echo = &testEcho{}
+	require.Nil(t, client.PostJSON("items", &TestPost{}, echo))
+	assert.Equal(t, http.MethodPost, echo.Method)
+	assert.Equal(t, "application/json", echo.ContentType)
+	assert.Equal(t, "acme", echo.Tenant)
+
+	_, err = NewAPIClientFromConfig(&ClientConfig{})
+	assert.IsType(t, &ConfigError{}, err)
+
+	_, err = NewAPIClientFromConfig(&ClientConfig{TimeoutMs: 1000, TLS: TLSConfig{CAFile: "not-exists.pem"}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,259 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobesa/go-domain-util/domainutil"
+)
+
+//ErrNoCookieJar returned by LoginSession when client has no cookie jar
+var ErrNoCookieJar = errors.New("api client has no cookie jar")
+
+//ErrNoSessionCookie returned by LoginSession when login response
+//did not set any cookie
+var ErrNoSessionCookie = errors.New("login response has no session cookie")
+
+//domainSuffixList is cookiejar.PublicSuffixList based on domainutil
+type domainSuffixList struct{}
+
+func (domainSuffixList) PublicSuffix(domain string) string {
+	if suffix := domainutil.DomainSuffix(domain); len(suffix) > 0 {
+		return suffix
+	}
+	return domain[strings.LastIndex(domain, ".")+1:]
+}
+
+func (domainSuffixList) String() string {
+	return "github.com/bobesa/go-domain-util/domainutil"
+}
+
+//CookieJar is http.CookieJar with public suffix aware domain scoping.
+//Jar created by NewFileCookieJar can be saved to file and restored later.
+type CookieJar struct {
+	jar  *cookiejar.Jar
+	path string
+
+	mu      sync.Mutex
+	entries []*storedCookie
+	index   map[string]int
+}
+
+//storedCookie is cookie with url it was received from
+type storedCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+func (s *storedCookie) key() string {
+	domain := s.Cookie.Domain
+	if len(domain) == 0 {
+		if u, err := url.Parse(s.URL); err == nil {
+			domain = u.Hostname()
+		}
+	}
+	return strings.ToLower(strings.TrimPrefix(domain, ".")) + ";" + s.Cookie.Path + ";" + s.Cookie.Name
+}
+
+//NewCookieJar create in-memory cookie jar
+func NewCookieJar() *CookieJar {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: domainSuffixList{}})
+	return &CookieJar{
+		jar:   jar,
+		index: make(map[string]int),
+	}
+}
+
+//NewFileCookieJar create cookie jar persisted in file at path.
+//Cookies are loaded from file when it exists, Save writes them back.
+func NewFileCookieJar(path string) (*CookieJar, error) {
+	j := NewCookieJar()
+	j.path = path
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*storedCookie
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.Cookie == nil || expired(entry.Cookie, now) {
+			continue
+		}
+		u, err := url.Parse(entry.URL)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{entry.Cookie})
+	}
+	return j, nil
+}
+
+//SetCookies implements http.CookieJar
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	if len(j.path) == 0 {
+		return
+	}
+	//query may hold tokens, so it is not written to jar file
+	origin := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	for _, cookie := range cookies {
+		//cookies rejected by jar are not stored,
+		//deleted ones replace stored cookie and are dropped by Save
+		if !expired(cookie, now) && !j.accepted(origin, cookie) {
+			continue
+		}
+		//relative max age is converted to expiration time for storing
+		if cookie.MaxAge > 0 {
+			copied := *cookie
+			copied.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+			copied.MaxAge = 0
+			cookie = &copied
+		}
+		entry := &storedCookie{URL: origin.String(), Cookie: cookie}
+		if i, ok := j.index[entry.key()]; ok {
+			j.entries[i] = entry
+			continue
+		}
+		j.index[entry.key()] = len(j.entries)
+		j.entries = append(j.entries, entry)
+	}
+}
+
+//accepted check that jar returns cookie received from u
+func (j *CookieJar) accepted(u *url.URL, cookie *http.Cookie) bool {
+	check := *u
+	if cookie.Secure {
+		check.Scheme = "https"
+	}
+	if strings.HasPrefix(cookie.Path, "/") {
+		check.Path = cookie.Path
+	}
+	for _, c := range j.jar.Cookies(&check) {
+		if c.Name == cookie.Name && c.Value == cookie.Value {
+			return true
+		}
+	}
+	return false
+}
+
+//Cookies implements http.CookieJar
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+//Save write not expired cookies to jar file.
+//It does nothing for in-memory jar.
+func (j *CookieJar) Save() error {
+	if len(j.path) == 0 {
+		return nil
+	}
+
+	j.mu.Lock()
+	now := time.Now()
+	entries := make([]*storedCookie, 0, len(j.entries))
+	for _, entry := range j.entries {
+		if !expired(entry.Cookie, now) {
+			entries = append(entries, entry)
+		}
+	}
+	j.mu.Unlock()
+
+	data, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	//write to temp file first, so jar file is never partially written
+	tmp, err := ioutil.TempFile(filepath.Dir(j.path), filepath.Base(j.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), j.path)
+}
+
+//expired check that cookie is deleted or has expired
+func expired(cookie *http.Cookie, now time.Time) bool {
+	if cookie.MaxAge < 0 {
+		return true
+	}
+	return !cookie.Expires.IsZero() && !cookie.Expires.After(now)
+}
+
+// WithCookieJar setup cookie jar for client requests
+func (c *APIClient) WithCookieJar(jar http.CookieJar) *APIClient {
+	c.client.Jar = jar
+	return c
+}
+
+// LoginSession send post request with JSON credentials to loginURL
+// and check that response set cookie, it is stored by client cookie jar.
+// Response body is stored in resp when it is not nil.
+func (c *APIClient) LoginSession(ctx context.Context, loginURL string, credentials, resp interface{}) error {
+	if c.client.Jar == nil {
+		return ErrNoCookieJar
+	}
+
+	var setCookies bool
+	ctx = context.WithValue(ctx, setCookiesKey{}, &setCookies)
+	if err := c.PostJSONContext(ctx, loginURL, credentials, resp); err != nil {
+		return err
+	}
+
+	if !setCookies {
+		return ErrNoSessionCookie
+	}
+	return nil
+}
+
+type setCookiesKey struct{}
+
+//recordSetCookies marks call context when response
+//or redirect responses before it have Set-Cookie header
+func recordSetCookies(req *http.Request, res *http.Response) {
+	found, ok := req.Context().Value(setCookiesKey{}).(*bool)
+	if !ok {
+		return
+	}
+	for r := res; r != nil; {
+		if len(r.Cookies()) > 0 {
+			*found = true
+			return
+		}
+		if r.Request == nil {
+			break
+		}
+		r = r.Request.Response
+	}
+}
@@ -0,0 +1,137 @@
+package util
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieJarDomainScoping(t *testing.T) {
+	jar := NewCookieJar()
+	u, _ := url.Parse("https://www.example.com/")
+
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "site", Value: "1", Domain: "example.com"},
+		{Name: "suffix", Value: "2", Domain: "com"},
+		{Name: "host", Value: "3"},
+	})
+
+	other, _ := url.Parse("https://api.example.com/")
+	assert.Equal(t, []string{"site"}, cookieNames(jar.Cookies(other)))
+	assert.ElementsMatch(t, []string{"site", "host"}, cookieNames(jar.Cookies(u)))
+
+	foreign, _ := url.Parse("https://other.com/")
+	assert.Empty(t, jar.Cookies(foreign))
+}
+
+func TestFileCookieJarPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookiejar")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cookies.json")
+
+	jar, err := NewFileCookieJar(path)
+	require.Nil(t, err)
+
+	u, _ := url.Parse("https://www.example.com/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "old", MaxAge: 3600},
+		{Name: "expired", Value: "1", Expires: time.Now().Add(-time.Hour)},
+	})
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "new", MaxAge: 3600}})
+	require.Nil(t, jar.Save())
+
+	restored, err := NewFileCookieJar(path)
+	require.Nil(t, err)
+	cookies := restored.Cookies(u)
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "session", cookies[0].Name)
+	assert.Equal(t, "new", cookies[0].Value)
+
+	require.Nil(t, NewCookieJar().Save())
+}
+
+func TestFileCookieJarStoredURL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookiejar")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cookies.json")
+
+	jar, err := NewFileCookieJar(path)
+	require.Nil(t, err)
+
+	u, _ := url.Parse("https://www.example.com/auth/callback?token=secret")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "abc", Path: "/"},
+		{Name: "secure", Value: "1", Secure: true},
+		//rejected by jar, domain does not match url
+		{Name: "foreign", Value: "1", Domain: "other.com"},
+	})
+	require.Nil(t, jar.Save())
+
+	data, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	assert.NotContains(t, string(data), "secret")
+	assert.NotContains(t, string(data), "foreign")
+	assert.Contains(t, string(data), `"url": "https://www.example.com/auth/callback"`)
+
+	restored, err := NewFileCookieJar(path)
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"session", "secure"}, cookieNames(restored.Cookies(u)))
+}
+
+func TestAPIClientLoginSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			w.Write([]byte(`{}`))
+		case "/me":
+			cookie, err := r.Cookie("session")
+			if err != nil || cookie.Value != "abc" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"id":1}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	credentials := map[string]string{"login": "user", "password": "pass"}
+
+	client := NewAPIClient(1000)
+	assert.Equal(t, ErrNoCookieJar, client.LoginSession(ctx, server.URL+"/login", credentials, nil))
+
+	client.WithCookieJar(NewCookieJar())
+	post := &TestPost{}
+	require.NotNil(t, client.GetJSON(server.URL+"/me", post))
+	require.Nil(t, client.LoginSession(ctx, server.URL+"/login", credentials, nil))
+	require.Nil(t, client.GetJSON(server.URL+"/me", post))
+	assert.Equal(t, 1, post.ID)
+
+	//relogin gets same session cookie
+	require.Nil(t, client.LoginSession(ctx, server.URL+"/login", credentials, nil))
+
+	assert.Equal(t, ErrNoSessionCookie,
+		client.LoginSession(ctx, server.URL+"/other", credentials, nil))
+}
+
+func cookieNames(cookies []*http.Cookie) []string {
+	names := make([]string, len(cookies))
+	for i, cookie := range cookies {
+		names[i] = cookie.Name
+	}
+	return names
+}
@@ -0,0 +1,328 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+//DebugLogger receives request and response dumps.
+//*log.Logger satisfies this interface.
+type DebugLogger interface {
+	Printf(format string, v ...interface{})
+}
+
+//DefaultDebugBodyLimit max number of body bytes in dump by default
+const DefaultDebugBodyLimit = 4096
+
+//redactedValue replace secret values in dumps
+const redactedValue = "[REDACTED]"
+
+//defaultRedactedHeaders always redacted in dumps
+var defaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+//DebugOptions configure APIClient request and response dumps
+type DebugOptions struct {
+	//Logger receives dumps, standard logger of log package when nil
+	Logger DebugLogger
+
+	//MaxBodyBytes limits number of body bytes in dump.
+	//DefaultDebugBodyLimit when zero, negative value disables body dump.
+	MaxBodyBytes int
+
+	//RedactHeaders additional header names with secret values.
+	//Authorization, Proxy-Authorization, Cookie and Set-Cookie
+	//are always redacted.
+	RedactHeaders []string
+
+	//RedactJSONFields names of JSON object fields and url query
+	//parameters with secret values, matched case insensitive,
+	//JSON fields at any nesting level. Values are masked in place,
+	//so order of fields in dump is kept.
+	RedactJSONFields []string
+}
+
+//debugDumper writes redacted request and response dumps
+type debugDumper struct {
+	logger       DebugLogger
+	maxBodyBytes int
+	headers      map[string]bool
+	fields       map[string]bool
+	fieldsRegexp *regexp.Regexp
+}
+
+//stdDebugLogger writes dumps by standard logger of log package
+type stdDebugLogger struct{}
+
+func (stdDebugLogger) Printf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
+
+func newDebugDumper(opts DebugOptions) *debugDumper {
+	if opts.Logger == nil {
+		opts.Logger = stdDebugLogger{}
+	}
+	d := &debugDumper{
+		logger:       opts.Logger,
+		maxBodyBytes: opts.MaxBodyBytes,
+		headers:      make(map[string]bool),
+		fields:       make(map[string]bool),
+	}
+	if d.maxBodyBytes == 0 {
+		d.maxBodyBytes = DefaultDebugBodyLimit
+	}
+	for _, name := range append(defaultRedactedHeaders, opts.RedactHeaders...) {
+		d.headers[http.CanonicalHeaderKey(name)] = true
+	}
+
+	quoted := make([]string, 0, len(opts.RedactJSONFields))
+	for _, name := range DistinctLowerCase(opts.RedactJSONFields) {
+		d.fields[name] = true
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	if len(quoted) > 0 {
+		//fallback for truncated bodies, which can not be parsed
+		d.fieldsRegexp = regexp.MustCompile(
+			`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[-+.\w]+)`)
+	}
+	return d
+}
+
+//dumpRequest log request line, headers and body.
+//Request body is restored for sending.
+func (d *debugDumper) dumpRequest(req *http.Request) {
+	if d == nil {
+		return
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody && d.maxBodyBytes > 0 {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			d.logger.Printf("> %s %s\n> read body error: %v", req.Method, d.redactURL(req.URL), err)
+			return
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "> %s %s\n", req.Method, d.redactURL(req.URL))
+	d.writeHeaders(buf, "> ", req.Header)
+	d.writeBody(buf, "> ", body, len(body))
+	d.logger.Printf("%s", buf.String())
+}
+
+//dumpResponse wrap response body, dump is logged when body is closed
+//so only bytes read by client are captured
+func (d *debugDumper) dumpResponse(req *http.Request, res *http.Response, start time.Time) {
+	if d == nil {
+		return
+	}
+	res.Body = &debugBody{
+		ReadCloser: res.Body,
+		dumper:     d,
+		req:        req,
+		res:        res,
+		start:      start,
+	}
+}
+
+//dumpError log transport error
+func (d *debugDumper) dumpError(req *http.Request, err error, start time.Time) {
+	if d == nil {
+		return
+	}
+	//url of error may contain secret query parameters
+	if urlErr, ok := err.(*url.Error); ok {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			redacted := *urlErr
+			redacted.URL = d.redactURL(u)
+			err = &redacted
+		}
+	}
+	d.logger.Printf("< %s %s error after %s: %v", req.Method, d.redactURL(req.URL), time.Since(start), err)
+}
+
+//redactURL return url with values of secret query parameters replaced,
+//order of parameters is kept
+func (d *debugDumper) redactURL(u *url.URL) string {
+	if len(d.fields) == 0 || len(u.RawQuery) == 0 {
+		return u.String()
+	}
+
+	params := strings.Split(u.RawQuery, "&")
+	for i, param := range params {
+		name := strings.SplitN(param, "=", 2)[0]
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if d.fields[strings.ToLower(name)] {
+			params[i] = strings.SplitN(param, "=", 2)[0] + "=" + redactedValue
+		}
+	}
+	redacted := *u
+	redacted.RawQuery = strings.Join(params, "&")
+	return redacted.String()
+}
+
+func (d *debugDumper) writeHeaders(buf *bytes.Buffer, prefix string, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range header[name] {
+			if d.headers[http.CanonicalHeaderKey(name)] {
+				value = redactedValue
+			}
+			fmt.Fprintf(buf, "%s%s: %s\n", prefix, name, value)
+		}
+	}
+}
+
+func (d *debugDumper) writeBody(buf *bytes.Buffer, prefix string, body []byte, total int) {
+	if len(body) == 0 {
+		return
+	}
+
+	truncated := false
+	if d.maxBodyBytes > 0 && len(body) > d.maxBodyBytes {
+		body = body[:d.maxBodyBytes]
+		truncated = true
+	}
+
+	buf.WriteString(prefix + "\n")
+	for _, line := range strings.Split(string(d.redactBody(body)), "\n") {
+		buf.WriteString(prefix + line + "\n")
+	}
+	if truncated {
+		fmt.Fprintf(buf, "%s... (truncated, %d of %d bytes)\n", prefix, d.maxBodyBytes, total)
+	}
+}
+
+//redactBody replace values of secret JSON fields
+func (d *debugDumper) redactBody(body []byte) []byte {
+	if len(d.fields) == 0 {
+		return body
+	}
+
+	if redacted, ok := d.redactJSON(body); ok {
+		return redacted
+	}
+	return d.fieldsRegexp.ReplaceAll(body, []byte(`${1}"`+redactedValue+`"`))
+}
+
+//redactJSON replace values of secret fields in place, so formatting
+//and order of fields are kept. It fails for invalid or truncated JSON.
+func (d *debugDumper) redactJSON(body []byte) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	out := new(bytes.Buffer)
+	written := 0
+
+	//objects is stack of open containers, true for object
+	var objects []bool
+	expectKey := false
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false
+		}
+
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{':
+				objects = append(objects, true)
+				expectKey = true
+			case '[':
+				objects = append(objects, false)
+				expectKey = false
+			default:
+				objects = objects[:len(objects)-1]
+				expectKey = len(objects) > 0 && objects[len(objects)-1]
+			}
+			continue
+		}
+
+		if key, ok := token.(string); ok && expectKey {
+			expectKey = false
+			if !d.fields[strings.ToLower(key)] {
+				continue
+			}
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, false
+			}
+			end := int(dec.InputOffset())
+			out.Write(body[written : end-len(value)])
+			out.WriteString(`"` + redactedValue + `"`)
+			written = end
+		}
+		//value is finished, in object it is followed by key
+		expectKey = len(objects) > 0 && objects[len(objects)-1]
+	}
+	out.Write(body[written:])
+	return out.Bytes(), true
+}
+
+//debugBody captures first bytes of response body and logs
+//response dump on close
+type debugBody struct {
+	io.ReadCloser
+	dumper *debugDumper
+	req    *http.Request
+	res    *http.Response
+	start  time.Time
+
+	captured bytes.Buffer
+	total    int
+	logged   bool
+}
+
+func (b *debugBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.total += n
+	if rest := b.dumper.maxBodyBytes + 1 - b.captured.Len(); rest > 0 && n > 0 {
+		if rest > n {
+			rest = n
+		}
+		b.captured.Write(p[:rest])
+	}
+	return n, err
+}
+
+func (b *debugBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.logged {
+		return err
+	}
+	b.logged = true
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "< %s %s %s (%s)\n",
+		b.res.Status, b.req.Method, b.dumper.redactURL(b.req.URL), time.Since(b.start))
+	b.dumper.writeHeaders(buf, "< ", b.res.Header)
+	b.dumper.writeBody(buf, "< ", b.captured.Bytes(), b.total)
+	b.dumper.logger.Printf("%s", buf.String())
+	return err
+}
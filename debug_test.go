@@ -0,0 +1,149 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDebugLogger struct {
+	mu    sync.Mutex
+	dumps []string
+}
+
+func (l *testDebugLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	l.dumps = append(l.dumps, fmt.Sprintf(format, v...))
+	l.mu.Unlock()
+}
+
+type testLogin struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+func TestAPIClientDebugRedactsSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "cookie-secret"})
+		w.Write([]byte(`{"login":"user","token":"response-secret"}`))
+	}))
+	defer server.Close()
+
+	logger := &testDebugLogger{}
+	client := NewAPIClient(1000).
+		WithHeaders(map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": "Bearer header-secret",
+			"X-Api-Key":     "key-secret",
+		}).
+		WithDebug(DebugOptions{
+			Logger:           logger,
+			RedactHeaders:    []string{"x-api-key"},
+			RedactJSONFields: []string{"Password", "token"},
+		})
+
+	resp := &testLogin{}
+	err := client.PostJSON(server.URL+"/login", &testLogin{Login: "user", Password: "body-secret"}, resp)
+	require.Nil(t, err)
+	assert.Equal(t, "response-secret", resp.Token)
+
+	require.Len(t, logger.dumps, 2)
+	request, response := logger.dumps[0], logger.dumps[1]
+
+	assert.True(t, strings.HasPrefix(request, "> POST "+server.URL+"/login\n"))
+	assert.Contains(t, request, "> Authorization: [REDACTED]\n")
+	assert.Contains(t, request, "> X-Api-Key: [REDACTED]\n")
+	assert.Contains(t, request, `"login":"user"`)
+	assert.Contains(t, request, `"password":"[REDACTED]"`)
+
+	assert.True(t, strings.HasPrefix(response, "< 200 OK POST "))
+	assert.Contains(t, response, "< Set-Cookie: [REDACTED]\n")
+	assert.Contains(t, response, `"token":"[REDACTED]"`)
+
+	for _, dump := range logger.dumps {
+		assert.NotContains(t, dump, "secret")
+	}
+}
+
+func TestAPIClientDebugRedactsQueryAndKeepsOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"z":1, "Token":"response-secret","a":{"token":["x"],"b":2}}`))
+	}))
+	defer server.Close()
+
+	logger := &testDebugLogger{}
+	client := NewAPIClient(1000).WithDebug(DebugOptions{
+		Logger:           logger,
+		RedactJSONFields: []string{"token", "access_token"},
+	})
+
+	require.Nil(t, client.GetJSON(server.URL+"/items?page=2&access_token=query-secret&TOKEN=x", &TestPost{}))
+	require.Len(t, logger.dumps, 2)
+	assert.Contains(t, logger.dumps[0], "/items?page=2&access_token=[REDACTED]&TOKEN=[REDACTED]\n")
+	assert.Contains(t, logger.dumps[1], `{"z":1, "Token":"[REDACTED]","a":{"token":"[REDACTED]","b":2}}`)
+
+	require.NotNil(t, NewAPIClient(150).WithDebug(DebugOptions{
+		Logger:           logger,
+		RedactJSONFields: []string{"access_token"},
+	}).GetJSON("http://127.0.0.1:1/?access_token=query-secret", &TestPost{}))
+	for _, dump := range logger.dumps {
+		assert.NotContains(t, dump, "secret")
+	}
+}
+
+func TestAPIClientDebugTruncatesBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"token":"response-secret","body":"` + strings.Repeat("x", 100) + `"}`))
+	}))
+	defer server.Close()
+
+	logger := &testDebugLogger{}
+	client := NewAPIClient(1000).WithDebug(DebugOptions{
+		Logger:           logger,
+		MaxBodyBytes:     40,
+		RedactJSONFields: []string{"token"},
+	})
+
+	post := &TestPost{}
+	require.Nil(t, client.GetJSON(server.URL, post))
+
+	require.Len(t, logger.dumps, 2)
+	assert.Contains(t, logger.dumps[1], `"token":"[REDACTED]"`)
+	assert.Contains(t, logger.dumps[1], "(truncated, 40 of 137 bytes)")
+	assert.NotContains(t, logger.dumps[1], "secret")
+}
+
+func TestAPIClientDebugTransportError(t *testing.T) {
+	logger := &testDebugLogger{}
+	client := NewAPIClient(150).WithDebug(DebugOptions{Logger: logger})
+
+	post := &TestPost{}
+	require.NotNil(t, client.GetJSON("http://127.0.0.1:1/", post))
+	require.Len(t, logger.dumps, 2)
+	assert.Contains(t, logger.dumps[1], "error after")
+}
+
+func TestAPIClientDebugDefaultLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+
+	client := NewAPIClient(1000).WithDebug(DebugOptions{})
+	require.Nil(t, client.GetJSON(server.URL, &TestPost{}))
+	assert.Contains(t, buf.String(), "GET "+server.URL)
+}
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+//JSONDecodeOptions configure APIClient response body decoding
+type JSONDecodeOptions struct {
+	//DisallowUnknownFields return error when response object has key
+	//which does not match any non-ignored, exported field of destination
+	DisallowUnknownFields bool
+
+	//UseNumber decode numbers into interface{} as json.Number
+	//instead of float64
+	UseNumber bool
+
+	//DisallowTrailingData return ErrJSONTrailingData when response body
+	//has anything but whitespace after JSON value
+	DisallowTrailingData bool
+}
+
+//ErrJSONTrailingData returned when response body has data after JSON value
+var ErrJSONTrailingData = errors.New("unexpected data after JSON value")
+
+//ResponseTooLargeError returned when response body exceeds
+//max response size of APIClient
+type ResponseTooLargeError struct {
+	URL   string
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds %d bytes, url %s", e.Limit, e.URL)
+}
+
+//decodeJSON decodes response body into resp
+//according to client size limit and decode options,
+//then validates it with schema of call and Validator of resp
+func (c *APIClient) decodeJSON(req *http.Request, res *http.Response, resp interface{}) error {
+	body := io.Reader(res.Body)
+	if c.maxResponseSize > 0 {
+		tooLarge := &ResponseTooLargeError{URL: req.URL.String(), Limit: c.maxResponseSize}
+		if res.ContentLength > c.maxResponseSize {
+			return tooLarge
+		}
+		body = &limitedBody{r: res.Body, left: c.maxResponseSize, err: tooLarge}
+	}
+
+	if schema := responseSchemaFromRequest(req); schema != nil {
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		if err := schema.ValidateJSON(data); err != nil {
+			if verr, ok := err.(*ValidationError); ok {
+				verr.URL = req.URL.String()
+			}
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	dec := json.NewDecoder(body)
+	if c.decodeOptions.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if c.decodeOptions.UseNumber {
+		dec.UseNumber()
+	}
+
+	if err := dec.Decode(resp); err != nil {
+		return err
+	}
+
+	if c.decodeOptions.DisallowTrailingData {
+		if _, err := dec.Token(); err != io.EOF {
+			if tooLarge, ok := err.(*ResponseTooLargeError); ok {
+				return tooLarge
+			}
+			return ErrJSONTrailingData
+		}
+	}
+	return c.validateResponse(req, resp)
+}
+
+//limitedBody reads at most left bytes and returns err
+//when underlying reader has more data
+type limitedBody struct {
+	r    io.Reader
+	left int64
+	err  error
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.left < 0 {
+		return 0, l.err
+	}
+	//read one extra byte to detect exceeding of limit
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	if l.left < 0 {
+		return n + int(l.left), l.err
+	}
+	return n, err
+}
@@ -0,0 +1,84 @@
+package util
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBodyServer(body string, chunked bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if chunked {
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+func TestAPIClientMaxResponseSize(t *testing.T) {
+	body := `{"id":1,"title":"` + strings.Repeat("x", 100) + `"}`
+
+	for _, chunked := range []bool{false, true} {
+		server := newTestBodyServer(body, chunked)
+
+		post := &TestPost{}
+		err := NewAPIClient(1000).WithMaxResponseSize(50).GetJSON(server.URL, post)
+		tooLarge, ok := err.(*ResponseTooLargeError)
+		require.True(t, ok, "chunked %v: %v", chunked, err)
+		assert.Equal(t, int64(50), tooLarge.Limit)
+
+		err = NewAPIClient(1000).WithMaxResponseSize(int64(len(body))).GetJSON(server.URL, post)
+		require.Nil(t, err)
+		assert.Equal(t, 1, post.ID)
+
+		server.Close()
+	}
+}
+
+func TestAPIClientJSONDecodeOptions(t *testing.T) {
+	server := newTestBodyServer(`{"id":1,"unknown":true}`, false)
+	defer server.Close()
+
+	post := &TestPost{}
+	require.Nil(t, NewAPIClient(1000).GetJSON(server.URL, post))
+
+	err := NewAPIClient(1000).
+		WithJSONDecodeOptions(JSONDecodeOptions{DisallowUnknownFields: true}).
+		GetJSON(server.URL, post)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unknown")
+
+	var value map[string]interface{}
+	err = NewAPIClient(1000).
+		WithJSONDecodeOptions(JSONDecodeOptions{UseNumber: true}).
+		GetJSON(server.URL, &value)
+	require.Nil(t, err)
+	assert.Equal(t, json.Number("1"), value["id"])
+}
+
+func TestAPIClientDisallowTrailingData(t *testing.T) {
+	client := NewAPIClient(1000).
+		WithJSONDecodeOptions(JSONDecodeOptions{DisallowTrailingData: true})
+
+	//key - response body
+	//val - expected error
+	testData := map[string]error{
+		`{"id":1}`:         nil,
+		"{\"id\":1}\n  \n": nil,
+		`{"id":1}garbage`:  ErrJSONTrailingData,
+		`{"id":1}{"id":2}`: ErrJSONTrailingData,
+		`{"id":1},`:        ErrJSONTrailingData,
+	}
+
+	for body, expected := range testData {
+		server := newTestBodyServer(body, false)
+		post := &TestPost{}
+		assert.Equal(t, expected, client.GetJSON(server.URL, post), body)
+		server.Close()
+	}
+}
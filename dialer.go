@@ -0,0 +1,29 @@
+package util
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+//DialContextFunc opens network connection to addr
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// WithDialer setup func for opening client connections.
+// Idle connections opened by previous dialer are closed.
+func (c *APIClient) WithDialer(dial DialContextFunc) *APIClient {
+	c.transport.DialContext = dial
+	c.transport.CloseIdleConnections()
+	return c
+}
+
+// WithUnixSocket send all client requests to unix domain socket.
+// Socket is path like "/var/run/app.sock" or "unix:///var/run/app.sock",
+// host of request url is ignored, e.g. "http://localhost/v1/status".
+func (c *APIClient) WithUnixSocket(socket string) *APIClient {
+	path := strings.TrimPrefix(socket, "unix://")
+	dialer := &net.Dialer{}
+	return c.WithDialer(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", path)
+	})
+}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIClientUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apiclient")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "api.sock")
+
+	listener, err := net.Listen("unix", socket)
+	require.Nil(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1,"title":"` + r.URL.Path + `"}`))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	for _, address := range []string{socket, "unix://" + socket} {
+		client := NewAPIClient(1000).WithUnixSocket(address)
+
+		post := &TestPost{}
+		require.Nil(t, client.GetJSON("http://localhost/v1/status", post))
+		assert.Equal(t, "/v1/status", post.Title)
+
+		post = &TestPost{}
+		require.Nil(t, client.PostJSON("http://unix/v1/items", post, post))
+		assert.Equal(t, "/v1/items", post.Title)
+	}
+}
+
+func TestAPIClientWithDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1}`))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	var dials int32
+	dialer := &net.Dialer{}
+	client := NewAPIClient(1000).WithDialer(
+		func(ctx context.Context, network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			assert.Equal(t, "api.internal:80", addr)
+			return dialer.DialContext(ctx, network, listener.Addr().String())
+		})
+
+	post := &TestPost{}
+	require.Nil(t, client.GetJSON("http://api.internal/", post))
+	assert.Equal(t, 1, post.ID)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dials))
+}
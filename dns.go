@@ -0,0 +1,196 @@
+package util
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+//DefaultDNSCacheTTL how long resolved addresses are kept by default
+const DefaultDNSCacheTTL = time.Minute
+
+//DefaultDNSLookupTimeout how long DNSCache waits for underlying resolver by default
+const DefaultDNSLookupTimeout = 10 * time.Second
+
+//Resolver resolves host name to IP addresses, *net.Resolver implements it
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+//StaticResolver resolves host names by fixed table,
+//e.g. for pointing client to local test server
+type StaticResolver map[string][]string
+
+//LookupHost return addresses of host from table
+func (r StaticResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, ok := r[host]
+	if !ok || len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return append([]string(nil), addrs...), nil
+}
+
+//DNSCache is Resolver which keeps addresses resolved by
+//underlying resolver for ttl. Concurrent lookups of same host
+//share single underlying lookup, failed lookups are not cached.
+type DNSCache struct {
+	resolver      Resolver
+	ttl           time.Duration
+	lookupTimeout time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*dnsCacheEntry
+}
+
+type dnsCacheEntry struct {
+	//done is closed when lookup is finished
+	done    chan struct{}
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+//NewDNSCache create cache over resolver, net.DefaultResolver when nil.
+//DefaultDNSCacheTTL is used when ttl is not positive.
+func NewDNSCache(resolver Resolver, ttl time.Duration) *DNSCache {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	if ttl <= 0 {
+		ttl = DefaultDNSCacheTTL
+	}
+	return &DNSCache{
+		resolver:      resolver,
+		ttl:           ttl,
+		lookupTimeout: DefaultDNSLookupTimeout,
+		entries:       make(map[string]*dnsCacheEntry),
+	}
+}
+
+//SetLookupTimeout limit time of underlying lookup, DefaultDNSLookupTimeout
+//is used when timeout is not positive. Lookup which is not finished
+//in time fails with timeout *net.DNSError. It must be called before cache is used.
+func (c *DNSCache) SetLookupTimeout(timeout time.Duration) *DNSCache {
+	if timeout <= 0 {
+		timeout = DefaultDNSLookupTimeout
+	}
+	c.lookupTimeout = timeout
+	return c
+}
+
+//LookupHost return cached addresses of host or resolve it
+func (c *DNSCache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	if ok {
+		select {
+		case <-entry.done:
+			if time.Now().After(entry.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		entry = &dnsCacheEntry{done: make(chan struct{})}
+		c.entries[host] = entry
+		c.mu.Unlock()
+		c.resolve(host, entry)
+	} else {
+		c.mu.Unlock()
+	}
+
+	select {
+	case <-entry.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	return append([]string(nil), entry.addrs...), nil
+}
+
+//resolve lookup host in background, so cancelling of one caller
+//does not fail other callers waiting for same host.
+//Entry is finished after lookup timeout even when resolver hangs.
+func (c *DNSCache) resolve(host string, entry *dnsCacheEntry) {
+	type lookupResult struct {
+		addrs []string
+		err   error
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.lookupTimeout)
+		defer cancel()
+
+		results := make(chan lookupResult, 1)
+		go func() {
+			addrs, err := c.resolver.LookupHost(ctx, host)
+			results <- lookupResult{addrs: addrs, err: err}
+		}()
+		select {
+		case res := <-results:
+			entry.addrs, entry.err = res.addrs, res.err
+		case <-ctx.Done():
+			entry.err = &net.DNSError{Err: "lookup timeout", Name: host, IsTimeout: true}
+		}
+		entry.expires = time.Now().Add(c.ttl)
+
+		if entry.err != nil {
+			c.mu.Lock()
+			if c.entries[host] == entry {
+				delete(c.entries, host)
+			}
+			c.mu.Unlock()
+		}
+		close(entry.done)
+	}()
+}
+
+//Flush remove all cached addresses
+func (c *DNSCache) Flush() {
+	c.mu.Lock()
+	c.entries = make(map[string]*dnsCacheEntry)
+	c.mu.Unlock()
+}
+
+// WithResolver resolve request hosts by resolver instead of system resolver.
+// Addresses are tried in order until connection is opened.
+// Current dialer of client is used for connecting to resolved addresses.
+func (c *APIClient) WithResolver(resolver Resolver) *APIClient {
+	dial := c.transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return c.WithDialer(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		ips, err := resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ip := range ips {
+			var conn net.Conn
+			conn, err = dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, err
+	})
+}
+
+// WithDNSCache keep addresses resolved by system resolver for ttl
+func (c *APIClient) WithDNSCache(ttl time.Duration) *APIClient {
+	return c.WithResolver(NewDNSCache(nil, ttl))
+}
@@ -0,0 +1,116 @@
+package util
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingResolver struct {
+	Resolver
+	lookups int32
+}
+
+func (r *countingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	atomic.AddInt32(&r.lookups, 1)
+	time.Sleep(10 * time.Millisecond)
+	return r.Resolver.LookupHost(ctx, host)
+}
+
+func TestDNSCache(t *testing.T) {
+	resolver := &countingResolver{Resolver: StaticResolver{"api.test": {"127.0.0.1"}}}
+	cache := NewDNSCache(resolver, 50*time.Millisecond)
+	ctx := context.Background()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addrs, err := cache.LookupHost(ctx, "api.test")
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"127.0.0.1"}, addrs)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&resolver.lookups))
+
+	//failed lookups are not cached
+	_, err := cache.LookupHost(ctx, "unknown.test")
+	require.IsType(t, &net.DNSError{}, err)
+	_, err = cache.LookupHost(ctx, "unknown.test")
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&resolver.lookups))
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = cache.LookupHost(ctx, "api.test")
+	require.Nil(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&resolver.lookups))
+
+	cache.Flush()
+	_, err = cache.LookupHost(ctx, "api.test")
+	require.Nil(t, err)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&resolver.lookups))
+}
+
+//hangingResolver blocks until release is closed, ignoring context
+type hangingResolver struct {
+	release chan struct{}
+}
+
+func (r *hangingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	<-r.release
+	return []string{"127.0.0.1"}, nil
+}
+
+func TestDNSCacheLookupTimeout(t *testing.T) {
+	resolver := &hangingResolver{release: make(chan struct{})}
+	defer close(resolver.release)
+	cache := NewDNSCache(resolver, time.Minute).SetLookupTimeout(20 * time.Millisecond)
+
+	start := time.Now()
+	_, err := cache.LookupHost(context.Background(), "api.test")
+	dnsErr, ok := err.(*net.DNSError)
+	require.True(t, ok, "%v", err)
+	assert.True(t, dnsErr.Timeout())
+	assert.True(t, time.Since(start) < time.Second)
+
+	//failed lookup is not cached
+	_, err = cache.LookupHost(context.Background(), "api.test")
+	assert.NotNil(t, err)
+}
+
+func TestWithResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"host":"` + r.Host + `"}`))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	//first address is unreachable, client falls back to second
+	client := NewAPIClient(1000).WithResolver(NewDNSCache(StaticResolver{
+		"api.test": {"127.0.0.2", "127.0.0.1"},
+	}, time.Minute))
+
+	resp := &struct {
+		Host string `json:"host"`
+	}{}
+	require.Nil(t, client.GetJSON("http://api.test:"+serverURL.Port()+"/", resp))
+	assert.Equal(t, "api.test:"+serverURL.Port(), resp.Host)
+
+	err := client.GetJSON("http://unknown.test:"+serverURL.Port()+"/", resp)
+	require.IsType(t, &url.Error{}, err)
+	assert.IsType(t, &net.DNSError{}, err.(*url.Error).Err)
+
+	//ip addresses are not resolved
+	require.Nil(t, client.GetJSON(server.URL, resp))
+}
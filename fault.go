@@ -0,0 +1,188 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+)
+
+//ErrInjectedFault can be used as Fault.Error to simulate broken connection.
+//It wraps syscall.ECONNRESET, so it is retried as real connection reset.
+var ErrInjectedFault error = injectedFaultError{}
+
+type injectedFaultError struct{}
+
+func (injectedFaultError) Error() string {
+	return "injected fault: connection reset"
+}
+
+func (injectedFaultError) Unwrap() error {
+	return syscall.ECONNRESET
+}
+
+//Fault describes failure injected by FaultTransport.
+//Fault applies to requests accepted by URLPattern and Match
+//with given probability. Latency is added first, then request
+//hangs, fails, gets status or truncated body depending on fields.
+type Fault struct {
+	//Probability of applying fault to matched request, from 0 to 1
+	Probability float64
+
+	//URLPattern limits fault to matching urls, all urls when nil
+	URLPattern *regexp.Regexp
+
+	//Match limits fault to accepted requests, all requests when nil
+	Match func(req *http.Request) bool
+
+	//Latency delay before request is sent
+	Latency time.Duration
+
+	//Timeout makes request hang until its context is done
+	Timeout bool
+
+	//Error returned instead of response
+	Error error
+
+	//StatusCode of synthetic empty response, request is not sent
+	StatusCode int
+
+	//TruncateBody cuts response body after given number of bytes,
+	//then reading fails with io.ErrUnexpectedEOF. Zero disables truncation.
+	TruncateBody int
+}
+
+func (f *Fault) matches(req *http.Request) bool {
+	if f.URLPattern != nil && !f.URLPattern.MatchString(req.URL.String()) {
+		return false
+	}
+	return f.Match == nil || f.Match(req)
+}
+
+//FaultTransport is http.RoundTripper which injects faults
+//into requests of wrapped transport. Random decisions are made
+//by generator with fixed seed, so sequence of faults is reproducible.
+type FaultTransport struct {
+	next   http.RoundTripper
+	faults []Fault
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+//NewFaultTransport create transport which injects faults into next.
+//First fault which matches request and passes probability check is applied.
+func NewFaultTransport(next http.RoundTripper, seed int64, faults ...Fault) *FaultTransport {
+	return &FaultTransport{
+		next:   next,
+		faults: faults,
+		rnd:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+//RoundTrip implements http.RoundTripper
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fault := t.pick(req)
+	if fault == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			closeRequestBody(req)
+			return nil, req.Context().Err()
+		}
+	}
+
+	if fault.Timeout {
+		<-req.Context().Done()
+		closeRequestBody(req)
+		return nil, req.Context().Err()
+	}
+
+	if fault.Error != nil {
+		closeRequestBody(req)
+		return nil, fault.Error
+	}
+
+	if fault.StatusCode > 0 {
+		closeRequestBody(req)
+		return &http.Response{
+			Status:     http.StatusText(fault.StatusCode),
+			StatusCode: fault.StatusCode,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+			Request:    req,
+		}, nil
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil || fault.TruncateBody <= 0 {
+		return res, err
+	}
+	res.Body = &truncatedBody{ReadCloser: res.Body, left: fault.TruncateBody}
+	res.ContentLength = -1
+	return res, nil
+}
+
+//closeRequestBody close body of request which is not sent,
+//as RoundTripper must do even on error
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+//pick return fault for request or nil
+func (t *FaultTransport) pick(req *http.Request) *Fault {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range t.faults {
+		fault := &t.faults[i]
+		if !fault.matches(req) {
+			continue
+		}
+		if t.rnd.Float64() < fault.Probability {
+			return fault
+		}
+	}
+	return nil
+}
+
+//truncatedBody returns io.ErrUnexpectedEOF after left bytes
+type truncatedBody struct {
+	io.ReadCloser
+	left int
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if len(p) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= n
+	return n, err
+}
+
+// WithFaults inject faults into client requests for chaos testing.
+// Faults are applied in order, seed makes random decisions reproducible.
+func (c *APIClient) WithFaults(seed int64, faults ...Fault) *APIClient {
+	c.client.Transport = NewFaultTransport(c.client.Transport, seed, faults...)
+	return c
+}
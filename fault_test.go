@@ -0,0 +1,111 @@
+package util
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFaultTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1,"title":"` + strings.Repeat("x", 100) + `"}`))
+	}))
+}
+
+func TestAPIClientFaults(t *testing.T) {
+	server := newFaultTestServer()
+	defer server.Close()
+
+	client := NewAPIClient(200).WithFaults(1,
+		Fault{Probability: 1, URLPattern: regexp.MustCompile(`/error$`), Error: ErrInjectedFault},
+		Fault{Probability: 1, URLPattern: regexp.MustCompile(`/status$`), StatusCode: http.StatusServiceUnavailable},
+		Fault{Probability: 1, URLPattern: regexp.MustCompile(`/truncate$`), TruncateBody: 10},
+		Fault{Probability: 1, URLPattern: regexp.MustCompile(`/timeout$`), Timeout: true},
+		Fault{Probability: 1, URLPattern: regexp.MustCompile(`/slow$`), Latency: 50 * time.Millisecond},
+	)
+
+	post := &TestPost{}
+	err := client.GetJSON(server.URL+"/error", post)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), ErrInjectedFault.Error())
+
+	err = client.GetJSON(server.URL+"/status", post)
+	statusErr, ok := err.(*StatusError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+
+	assert.Equal(t, io.ErrUnexpectedEOF, client.GetJSON(server.URL+"/truncate", post))
+
+	start := time.Now()
+	require.NotNil(t, client.GetJSON(server.URL+"/timeout", post))
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+
+	start = time.Now()
+	require.Nil(t, client.GetJSON(server.URL+"/slow", post))
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+
+	require.Nil(t, client.GetJSON(server.URL+"/ok", post))
+}
+
+func TestFaultTransportSeedIsReproducible(t *testing.T) {
+	server := newFaultTestServer()
+	defer server.Close()
+
+	run := func(seed int64) []bool {
+		client := NewAPIClient(1000).WithFaults(seed,
+			Fault{Probability: 0.5, StatusCode: http.StatusInternalServerError})
+		var failed []bool
+		for i := 0; i < 20; i++ {
+			failed = append(failed, client.GetJSON(server.URL, &TestPost{}) != nil)
+		}
+		return failed
+	}
+
+	first := run(42)
+	assert.Equal(t, first, run(42))
+	assert.Contains(t, first, true)
+	assert.Contains(t, first, false)
+}
+
+//closeTrackingBody records that request body was closed
+type closeTrackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *closeTrackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestFaultTransportClosesRequestBody(t *testing.T) {
+	faults := []Fault{
+		{Probability: 1, Error: ErrInjectedFault},
+		{Probability: 1, StatusCode: http.StatusInternalServerError},
+		{Probability: 1, Timeout: true},
+		{Probability: 1, Latency: time.Second},
+	}
+
+	for _, fault := range faults {
+		transport := NewFaultTransport(http.DefaultTransport, 1, fault)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		body := &closeTrackingBody{Reader: strings.NewReader("{}")}
+		req, err := http.NewRequest(http.MethodPost, "http://test.com/", body)
+		require.Nil(t, err)
+
+		res, _ := transport.RoundTrip(req.WithContext(ctx))
+		cancel()
+		if res != nil {
+			res.Body.Close()
+		}
+		assert.True(t, body.closed, "%+v", fault)
+	}
+}
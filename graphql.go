@@ -0,0 +1,160 @@
+package util
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+//GraphQLLocation is position in query document related to error
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+//GraphQLError is item of GraphQL response errors array
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e *GraphQLError) Error() string {
+	return "graphql: " + e.Message
+}
+
+//GraphQLErrors returned when response has not empty errors array.
+//Response data is decoded anyway, so partial result is available.
+type GraphQLErrors []*GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return "graphql: " + strings.Join(messages, "; ")
+}
+
+//persistedQueryNotFound error code of automatic persisted queries
+const persistedQueryNotFound = "PERSISTED_QUERY_NOT_FOUND"
+
+//GraphQLClient sends GraphQL operations by APIClient.PostJSON
+type GraphQLClient struct {
+	client    *APIClient
+	url       string
+	persisted bool
+}
+
+type graphQLRequest struct {
+	Query      string                 `json:"query,omitempty"`
+	Variables  map[string]interface{} `json:"variables,omitempty"`
+	Extensions *graphQLExtensions     `json:"extensions,omitempty"`
+}
+
+type graphQLExtensions struct {
+	PersistedQuery *graphQLPersistedQuery `json:"persistedQuery,omitempty"`
+}
+
+type graphQLPersistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+//NewGraphQLClient create GraphQL client for endpoint url
+func NewGraphQLClient(client *APIClient, url string) *GraphQLClient {
+	return &GraphQLClient{client: client, url: url}
+}
+
+//WithPersistedQueries enable automatic persisted queries.
+//Query hash is sent first and full query only when server
+//does not know the hash yet.
+func (c *GraphQLClient) WithPersistedQueries() *GraphQLClient {
+	c.persisted = true
+	return c
+}
+
+//Do send query or mutation with variables and stores response data
+//in the value pointed to by data. Errors array of response
+//is returned as GraphQLErrors with HTTP 200 and error statuses.
+func (c *GraphQLClient) Do(ctx context.Context, query string, variables map[string]interface{}, data interface{}) error {
+	req := &graphQLRequest{Query: query, Variables: variables}
+
+	if c.persisted {
+		hash := sha256.Sum256([]byte(query))
+		req.Query = ""
+		req.Extensions = &graphQLExtensions{
+			PersistedQuery: &graphQLPersistedQuery{
+				Version:    1,
+				Sha256Hash: hex.EncodeToString(hash[:]),
+			},
+		}
+
+		res, err := c.send(ctx, req)
+		if err != nil {
+			return err
+		}
+		if !res.Errors.persistedQueryNotFound() {
+			return res.decode(data)
+		}
+		req.Query = query
+	}
+
+	res, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	return res.decode(data)
+}
+
+func (c *GraphQLClient) send(ctx context.Context, req *graphQLRequest) (*graphQLResponse, error) {
+	res := &graphQLResponse{}
+	var errBody []byte
+	err := c.client.PostJSONContext(contextWithErrorBody(ctx, &errBody), c.url, req, res)
+	if _, ok := err.(*StatusError); ok {
+		//error status may have GraphQL errors body, e.g. 400 for
+		//not found persisted query, other bodies keep status error
+		res = &graphQLResponse{}
+		if json.Unmarshal(errBody, res) != nil || len(res.Errors) == 0 {
+			return nil, err
+		}
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+//decode stores data in dst and return response errors
+func (r *graphQLResponse) decode(dst interface{}) error {
+	if dst != nil && len(r.Data) > 0 && string(r.Data) != "null" {
+		if err := json.Unmarshal(r.Data, dst); err != nil {
+			return err
+		}
+	}
+	if len(r.Errors) > 0 {
+		return r.Errors
+	}
+	return nil
+}
+
+//persistedQueryNotFound check that server asks for full query
+func (e GraphQLErrors) persistedQueryNotFound() bool {
+	for _, err := range e {
+		if code, _ := err.Extensions["code"].(string); code == persistedQueryNotFound {
+			return true
+		}
+		if err.Message == "PersistedQueryNotFound" {
+			return true
+		}
+	}
+	return false
+}
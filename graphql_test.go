@@ -0,0 +1,142 @@
+package util
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testGraphQLRequest struct {
+	Query      string                 `json:"query"`
+	Variables  map[string]interface{} `json:"variables"`
+	Extensions struct {
+		PersistedQuery *struct {
+			Sha256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+type testGraphQLData struct {
+	User *struct {
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+func TestGraphQLClientDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req testGraphQLRequest
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.Variables["id"] == "1" {
+			w.Write([]byte(`{"data":{"user":{"name":"John"}}}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"user":null},"errors":[{"message":"user not found",` +
+			`"locations":[{"line":1,"column":3}],"path":["user"],"extensions":{"code":"NOT_FOUND"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewGraphQLClient(NewAPIClient(1000), server.URL)
+	query := `{ user(id: $id) { name } }`
+
+	data := &testGraphQLData{}
+	require.Nil(t, client.Do(context.Background(), query, map[string]interface{}{"id": "1"}, data))
+	assert.Equal(t, "John", data.User.Name)
+
+	data = &testGraphQLData{}
+	err := client.Do(context.Background(), query, map[string]interface{}{"id": "2"}, data)
+	errs, ok := err.(GraphQLErrors)
+	require.True(t, ok)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "user not found", errs[0].Message)
+	assert.Equal(t, []GraphQLLocation{{Line: 1, Column: 3}}, errs[0].Locations)
+	assert.Equal(t, []interface{}{"user"}, errs[0].Path)
+	assert.Equal(t, "NOT_FOUND", errs[0].Extensions["code"])
+	assert.Nil(t, data.User)
+}
+
+func TestGraphQLClientPersistedQueries(t *testing.T) {
+	testGraphQLPersistedQueries(t, http.StatusOK)
+}
+
+func TestGraphQLClientPersistedQueriesBadRequest(t *testing.T) {
+	testGraphQLPersistedQueries(t, http.StatusBadRequest)
+}
+
+//testGraphQLPersistedQueries runs server which answers unknown hash
+//with PERSISTED_QUERY_NOT_FOUND error and notFoundStatus
+func testGraphQLPersistedQueries(t *testing.T, notFoundStatus int) {
+	var mu sync.Mutex
+	known := map[string]bool{}
+	var requests []testGraphQLRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req testGraphQLRequest
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		require.NotNil(t, req.Extensions.PersistedQuery)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, req)
+		hash := req.Extensions.PersistedQuery.Sha256Hash
+		if len(req.Query) > 0 {
+			known[hash] = true
+		}
+		if !known[hash] {
+			w.WriteHeader(notFoundStatus)
+			w.Write([]byte(`{"errors":[{"message":"PersistedQueryNotFound",` +
+				`"extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"user":{"name":"John"}}}`))
+	}))
+	defer server.Close()
+
+	client := NewGraphQLClient(NewAPIClient(1000), server.URL).WithPersistedQueries()
+	query := `{ user { name } }`
+
+	for i := 0; i < 2; i++ {
+		data := &testGraphQLData{}
+		require.Nil(t, client.Do(context.Background(), query, nil, data))
+		assert.Equal(t, "John", data.User.Name)
+	}
+
+	require.Len(t, requests, 3)
+	assert.Empty(t, requests[0].Query)
+	assert.Equal(t, query, requests[1].Query)
+	assert.Empty(t, requests[2].Query)
+	hash := sha256.Sum256([]byte(query))
+	assert.Equal(t, hex.EncodeToString(hash[:]), requests[0].Extensions.PersistedQuery.Sha256Hash)
+}
+
+func TestGraphQLClientErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors":[{"message":"syntax error"}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`<html>bad gateway</html>`))
+	}))
+	defer server.Close()
+
+	err := NewGraphQLClient(NewAPIClient(1000), server.URL+"/invalid").
+		Do(context.Background(), `{`, nil, &testGraphQLData{})
+	errs, ok := err.(GraphQLErrors)
+	require.True(t, ok)
+	assert.Equal(t, "syntax error", errs[0].Message)
+
+	err = NewGraphQLClient(NewAPIClient(1000), server.URL).
+		Do(context.Background(), `{ user { name } }`, nil, &testGraphQLData{})
+	statusErr, ok := err.(*StatusError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusBadGateway, statusErr.StatusCode)
+}
@@ -0,0 +1,274 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//HeaderIdempotencyKey idempotency key request header
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+//HeaderIdempotentReplayed response header set by IdempotencyMiddleware
+//when stored response is replayed
+const HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+//NewIdempotencyKey return random UUID version 4 string
+func NewIdempotencyKey() string {
+	var uuid [16]byte
+	rand.Read(uuid[:])
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+type idempotencyKeyKey struct{}
+
+//ContextWithIdempotencyKey return copy of ctx carrying idempotency key.
+//APIClient sends this key instead of generated one, so caller can reuse
+//it between several calls of one logical operation.
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+//IdempotencyKeyFromContext return idempotency key stored in ctx
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyKey{}).(string)
+	return key, ok && len(key) > 0
+}
+
+//setIdempotencyKey attach idempotency key to POST and PATCH requests
+//which have no such header yet
+func (c *APIClient) setIdempotencyKey(req *http.Request) {
+	if req.Method != http.MethodPost && req.Method != http.MethodPatch {
+		return
+	}
+	if len(req.Header.Get(HeaderIdempotencyKey)) > 0 {
+		return
+	}
+
+	key, ok := IdempotencyKeyFromContext(req.Context())
+	if !ok {
+		if c.newIdempotencyKey == nil {
+			return
+		}
+		key = c.newIdempotencyKey()
+	}
+	req.Header.Set(HeaderIdempotencyKey, key)
+}
+
+//StoredResponse is response saved by IdempotencyMiddleware
+type StoredResponse struct {
+	//RequestHash fingerprint of request method, path and body,
+	//key reused with other request is rejected
+	RequestHash string
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+}
+
+//ErrIdempotencyKeyInProgress returned by IdempotencyStore.Begin
+//when request with same key is processing now
+var ErrIdempotencyKeyInProgress = errors.New("idempotency key in progress")
+
+//IdempotencyStore stores responses by idempotency key.
+//Implementation must be safe for concurrent use.
+type IdempotencyStore interface {
+	//Begin reserve key for processing.
+	//Return stored response when key is already completed
+	//or ErrIdempotencyKeyInProgress when key is reserved.
+	Begin(key string) (*StoredResponse, error)
+
+	//Complete save response for reserved key
+	Complete(key string, res *StoredResponse)
+
+	//Abort release reserved key without saving response
+	Abort(key string)
+}
+
+//MemoryIdempotencyStore keeps responses in memory for ttl
+type MemoryIdempotencyStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+type idempotencyEntry struct {
+	res       *StoredResponse
+	expiresAt time.Time
+}
+
+//NewMemoryIdempotencyStore create in-memory store.
+//Completed responses expire after ttl, zero ttl means never.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+//Begin implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Begin(key string) (*StoredResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := s.entries[key]; ok {
+		if entry.res == nil {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+		if entry.expiresAt.IsZero() || now.Before(entry.expiresAt) {
+			return entry.res, nil
+		}
+	}
+
+	//remove expired entries while holding lock anyway
+	for k, entry := range s.entries {
+		if entry.res != nil && !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+	s.entries[key] = &idempotencyEntry{}
+	return nil, nil
+}
+
+//Complete implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Complete(key string, res *StoredResponse) {
+	entry := &idempotencyEntry{res: res}
+	if s.ttl > 0 {
+		entry.expiresAt = time.Now().Add(s.ttl)
+	}
+	s.mu.Lock()
+	s.entries[key] = entry
+	s.mu.Unlock()
+}
+
+//Abort implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Abort(key string) {
+	s.mu.Lock()
+	if entry, ok := s.entries[key]; ok && entry.res == nil {
+		delete(s.entries, key)
+	}
+	s.mu.Unlock()
+}
+
+//IdempotencyMiddleware stores responses of POST and PATCH requests
+//with Idempotency-Key header and replays them for repeated requests.
+//Concurrent request with same key gets 409 Conflict,
+//key reused with other method, path or body gets 422 Unprocessable Entity.
+//Server error responses are not stored, so request can be retried.
+func IdempotencyMiddleware(store IdempotencyStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderIdempotencyKey)
+		if len(key) == 0 || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			http.Error(w, "can not read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		hash := requestHash(r, body)
+
+		stored, err := store.Begin(key)
+		if err == ErrIdempotencyKeyInProgress {
+			http.Error(w, "request with same idempotency key in progress", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if stored != nil {
+			if stored.RequestHash != hash {
+				http.Error(w, "idempotency key reused with other request", http.StatusUnprocessableEntity)
+				return
+			}
+			replayResponse(w, stored)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		completed := false
+		defer func() {
+			if !completed {
+				store.Abort(key)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		if rec.statusCode >= http.StatusInternalServerError {
+			return
+		}
+		store.Complete(key, &StoredResponse{
+			RequestHash: hash,
+			StatusCode:  rec.statusCode,
+			Header:      cloneHeader(w.Header()),
+			Body:        rec.body.Bytes(),
+		})
+		completed = true
+	})
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	hasher := sha256.New()
+	hasher.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hasher.Write(body)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+func replayResponse(w http.ResponseWriter, stored *StoredResponse) {
+	for name, values := range stored.Header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.Header().Set(HeaderIdempotentReplayed, "true")
+	w.WriteHeader(stored.StatusCode)
+	w.Write(stored.Body)
+}
+
+func cloneHeader(header http.Header) http.Header {
+	clone := make(http.Header, len(header))
+	for name, values := range header {
+		clone[name] = append([]string(nil), values...)
+	}
+	return clone
+}
+
+//responseRecorder passes response to client and keeps its copy
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
@@ -0,0 +1,121 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIdempotencyKey(t *testing.T) {
+	key := NewIdempotencyKey()
+	assert.Len(t, key, 36)
+	assert.Equal(t, byte('4'), key[14])
+	assert.NotEqual(t, key, NewIdempotencyKey())
+}
+
+func TestAPIClientIdempotencyKeyReusedByRetries(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(HeaderIdempotencyKey))
+		failed := len(keys) < 3
+		mu.Unlock()
+		if failed {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(1000).
+		WithIdempotencyKeys(nil).
+		WithRetry(RetryPolicy{MaxAttempts: 3})
+
+	post := &TestPost{}
+	require.Nil(t, client.PostJSON(server.URL, &TestPost{Title: "foo"}, post))
+	require.Len(t, keys, 3)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+
+	require.Nil(t, client.PostJSON(server.URL, &TestPost{Title: "foo"}, post))
+	require.Len(t, keys, 4)
+	assert.NotEqual(t, keys[0], keys[3])
+
+	ctx := ContextWithIdempotencyKey(context.Background(), "caller-key")
+	require.Nil(t, client.PostJSONContext(ctx, server.URL, &TestPost{Title: "foo"}, post))
+	assert.Equal(t, "caller-key", keys[4])
+
+	require.Nil(t, client.GetJSON(server.URL, post))
+	assert.Empty(t, keys[5])
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	var calls int32
+	handler := IdempotencyMiddleware(NewMemoryIdempotencyStore(0),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&calls, 1)
+			if strings.Contains(r.URL.Path, "fail") {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("X-Call", string('0'+rune(n)))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":1}`))
+		}))
+
+	send := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(HeaderIdempotencyKey, key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send("/payments", "key-1", `{"amount":1}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(HeaderIdempotentReplayed))
+
+	replay := send("/payments", "key-1", `{"amount":1}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "1", replay.Header().Get("X-Call"))
+	assert.Equal(t, "true", replay.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, `{"id":1}`, replay.Body.String())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	mismatch := send("/payments", "key-1", `{"amount":2}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, mismatch.Code)
+
+	assert.Equal(t, http.StatusInternalServerError, send("/fail", "key-2", "").Code)
+	assert.Equal(t, http.StatusInternalServerError, send("/fail", "key-2", "").Code)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestMemoryIdempotencyStoreInProgress(t *testing.T) {
+	store := NewMemoryIdempotencyStore(0)
+
+	stored, err := store.Begin("key")
+	require.Nil(t, err)
+	require.Nil(t, stored)
+
+	_, err = store.Begin("key")
+	assert.Equal(t, ErrIdempotencyKeyInProgress, err)
+
+	store.Abort("key")
+	_, err = store.Begin("key")
+	require.Nil(t, err)
+
+	store.Complete("key", &StoredResponse{StatusCode: http.StatusOK})
+	stored, err = store.Begin("key")
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, stored.StatusCode)
+}
@@ -0,0 +1,247 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+)
+
+//jsonRPCVersion protocol version sent in every request
+const jsonRPCVersion = "2.0"
+
+//JSON-RPC 2.0 predefined error codes
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+)
+
+//ErrJSONRPCNoResponse set for batch call when server response
+//has no object with call id
+var ErrJSONRPCNoResponse = errors.New("jsonrpc: no response for call")
+
+//ErrJSONRPCEmptyBatch returned for batch without calls
+var ErrJSONRPCEmptyBatch = errors.New("jsonrpc: empty batch")
+
+//JSONRPCError is error object returned by JSON-RPC server
+type JSONRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+//JSONRPCCall is single call of batch request
+type JSONRPCCall struct {
+	Method string
+	Params interface{}
+
+	//Result destination for call result, may be nil
+	Result interface{}
+
+	//Notification call is sent without id and gets no response
+	Notification bool
+
+	//Err is set after batch execution: *JSONRPCError returned by server,
+	//result decoding error or ErrJSONRPCNoResponse
+	Err error
+}
+
+//JSONRPCClient is JSON-RPC 2.0 over HTTP client.
+//Requests are sent by APIClient, so its headers,
+//success status detection and other options are applied.
+type JSONRPCClient struct {
+	client *APIClient
+	url    string
+	nextID uint64
+}
+
+type jsonRPCRequest struct {
+	Version string `json:"jsonrpc"`
+	Method  string `json:"method"`
+
+	//Params is omitted when call params are nil or encoded as null,
+	//e.g. nil map or slice, empty array or object is sent as is
+	Params json.RawMessage `json:"params,omitempty"`
+	ID     *uint64         `json:"id,omitempty"`
+}
+
+type jsonRPCResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *JSONRPCError   `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+//NewJSONRPCClient create JSON-RPC client for endpoint url
+func NewJSONRPCClient(client *APIClient, url string) *JSONRPCClient {
+	return &JSONRPCClient{client: client, url: url}
+}
+
+func (c *JSONRPCClient) newRequest(method string, params interface{}, notification bool) (*jsonRPCRequest, error) {
+	req := &jsonRPCRequest{Version: jsonRPCVersion, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		//null params are not allowed by JSON-RPC 2.0
+		if string(data) != "null" {
+			req.Params = data
+		}
+	}
+	if !notification {
+		id := atomic.AddUint64(&c.nextID, 1)
+		req.ID = &id
+	}
+	return req, nil
+}
+
+//postNotifications send request which has only notifications.
+//Server may answer with any 2xx status and empty body.
+func (c *JSONRPCClient) postNotifications(ctx context.Context, body interface{}) error {
+	err := c.client.PostJSONContext(ctx, c.url, body, nil)
+	if statusErr, ok := err.(*StatusError); ok &&
+		statusErr.StatusCode >= 200 && statusErr.StatusCode < 300 {
+		return nil
+	}
+	return err
+}
+
+//Call invoke method with params and stores call result
+//in the value pointed to by result.
+//Error object of response is returned as *JSONRPCError.
+func (c *JSONRPCClient) Call(ctx context.Context, method string, params, result interface{}) error {
+	req, err := c.newRequest(method, params, false)
+	if err != nil {
+		return err
+	}
+
+	res := &jsonRPCResponse{}
+	if err := c.client.PostJSONContext(ctx, c.url, req, res); err != nil {
+		return err
+	}
+	if res.Version != jsonRPCVersion {
+		return fmt.Errorf("jsonrpc: unexpected version %q", res.Version)
+	}
+	//error object has null id when request could not be parsed
+	if res.Error == nil && jsonRPCIDKey(res.ID) != strconv.FormatUint(*req.ID, 10) {
+		return fmt.Errorf("jsonrpc: response id %s does not match request id %d", res.ID, *req.ID)
+	}
+	return res.decode(result)
+}
+
+//Notify send notification, server response body is ignored
+//and any 2xx status is accepted
+func (c *JSONRPCClient) Notify(ctx context.Context, method string, params interface{}) error {
+	req, err := c.newRequest(method, params, true)
+	if err != nil {
+		return err
+	}
+	return c.postNotifications(ctx, req)
+}
+
+//Batch send all calls in one request. Returned error describes
+//request failure, result of every call is stored in its Result and Err.
+func (c *JSONRPCClient) Batch(ctx context.Context, calls []*JSONRPCCall) error {
+	if len(calls) == 0 {
+		return ErrJSONRPCEmptyBatch
+	}
+
+	reqs := make([]*jsonRPCRequest, len(calls))
+	byID := make(map[string]*JSONRPCCall, len(calls))
+	for i, call := range calls {
+		req, err := c.newRequest(call.Method, call.Params, call.Notification)
+		if err != nil {
+			return err
+		}
+		reqs[i] = req
+		if !call.Notification {
+			byID[strconv.FormatUint(*reqs[i].ID, 10)] = call
+		}
+	}
+
+	if len(byID) == 0 {
+		return c.postNotifications(ctx, reqs)
+	}
+
+	var raw json.RawMessage
+	if err := c.client.PostJSONContext(ctx, c.url, reqs, &raw); err != nil {
+		return err
+	}
+
+	//server returns single error object when batch itself is invalid
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '{' {
+		res := &jsonRPCResponse{}
+		if err := json.Unmarshal(raw, res); err != nil {
+			return err
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+		return errors.New("jsonrpc: batch response is not array")
+	}
+
+	var responses []*jsonRPCResponse
+	if err := json.Unmarshal(raw, &responses); err != nil {
+		return err
+	}
+
+	for _, call := range byID {
+		call.Err = ErrJSONRPCNoResponse
+	}
+	for _, res := range responses {
+		call, ok := byID[jsonRPCIDKey(res.ID)]
+		if !ok {
+			continue
+		}
+		call.Err = res.decode(call.Result)
+	}
+	return nil
+}
+
+//jsonRPCIDKey normalize response id for matching with request id,
+//so numbers like 1, 1.0 and 1e0 are equal and whitespace is ignored
+func jsonRPCIDKey(raw json.RawMessage) string {
+	var id interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&id); err != nil {
+		return string(raw)
+	}
+
+	switch v := id.(type) {
+	case json.Number:
+		if f, ok := new(big.Float).SetString(v.String()); ok && f.IsInt() {
+			if n, accuracy := f.Uint64(); accuracy == big.Exact {
+				return strconv.FormatUint(n, 10)
+			}
+		}
+		return v.String()
+	case string:
+		return strconv.Quote(v)
+	}
+	return string(bytes.TrimSpace(raw))
+}
+
+//decode return response error or stores result in dst
+func (r *jsonRPCResponse) decode(dst interface{}) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if dst == nil || len(r.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Result, dst)
+}
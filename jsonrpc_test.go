@@ -0,0 +1,170 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRPCRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  []int           `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+//handleTestRPC implements "sum" method, other methods are not found
+func handleTestRPC(req testRPCRequest) map[string]interface{} {
+	res := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if req.Method != "sum" {
+		res["error"] = map[string]interface{}{"code": JSONRPCMethodNotFound, "message": "Method not found"}
+		return res
+	}
+	sum := 0
+	for _, v := range req.Params {
+		sum += v
+	}
+	res["result"] = sum
+	return res
+}
+
+func newTestRPCServer(t *testing.T, notifications *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw json.RawMessage
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&raw))
+
+		if raw[0] == '[' {
+			var reqs []testRPCRequest
+			require.Nil(t, json.Unmarshal(raw, &reqs))
+			var responses []interface{}
+			for i := len(reqs) - 1; i >= 0; i-- {
+				if reqs[i].ID == nil {
+					*notifications = append(*notifications, reqs[i].Method)
+					continue
+				}
+				responses = append(responses, handleTestRPC(reqs[i]))
+			}
+			if len(responses) == 0 {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			json.NewEncoder(w).Encode(responses)
+			return
+		}
+
+		var req testRPCRequest
+		require.Nil(t, json.Unmarshal(raw, &req))
+		assert.Equal(t, "2.0", req.Version)
+		if req.ID == nil {
+			*notifications = append(*notifications, req.Method)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(handleTestRPC(req))
+	}))
+}
+
+func TestJSONRPCClientCall(t *testing.T) {
+	var notifications []string
+	server := newTestRPCServer(t, &notifications)
+	defer server.Close()
+
+	client := NewJSONRPCClient(NewAPIClient(1000), server.URL)
+	ctx := context.Background()
+
+	var sum int
+	require.Nil(t, client.Call(ctx, "sum", []int{1, 2, 3}, &sum))
+	assert.Equal(t, 6, sum)
+
+	err := client.Call(ctx, "unknown", nil, &sum)
+	rpcErr, ok := err.(*JSONRPCError)
+	require.True(t, ok)
+	assert.Equal(t, JSONRPCMethodNotFound, rpcErr.Code)
+
+	require.Nil(t, client.Notify(ctx, "log", []int{1}))
+	assert.Equal(t, []string{"log"}, notifications)
+}
+
+func TestJSONRPCClientBatch(t *testing.T) {
+	var notifications []string
+	server := newTestRPCServer(t, &notifications)
+	defer server.Close()
+
+	client := NewJSONRPCClient(NewAPIClient(1000), server.URL)
+	ctx := context.Background()
+
+	var first, second int
+	calls := []*JSONRPCCall{
+		{Method: "sum", Params: []int{1, 2}, Result: &first},
+		{Method: "log", Notification: true},
+		{Method: "unknown"},
+		{Method: "sum", Params: []int{3, 4}, Result: &second},
+	}
+	require.Nil(t, client.Batch(ctx, calls))
+	assert.Nil(t, calls[0].Err)
+	assert.Equal(t, 3, first)
+	assert.Nil(t, calls[1].Err)
+	rpcErr, ok := calls[2].Err.(*JSONRPCError)
+	require.True(t, ok)
+	assert.Equal(t, JSONRPCMethodNotFound, rpcErr.Code)
+	assert.Nil(t, calls[3].Err)
+	assert.Equal(t, 7, second)
+	assert.Equal(t, []string{"log"}, notifications)
+
+	require.Nil(t, client.Batch(ctx, []*JSONRPCCall{{Method: "ping", Notification: true}}))
+	assert.Equal(t, []string{"log", "ping"}, notifications)
+
+	assert.Equal(t, ErrJSONRPCEmptyBatch, client.Batch(ctx, nil))
+}
+
+func TestJSONRPCClientParams(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, strings.TrimSpace(string(body)))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := NewJSONRPCClient(NewAPIClient(1000), server.URL)
+	ctx := context.Background()
+	require.Nil(t, client.Notify(ctx, "a", []int{}))
+	require.Nil(t, client.Notify(ctx, "b", map[string]int{}))
+	require.Nil(t, client.Notify(ctx, "c", nil))
+	assert.NotNil(t, client.Notify(ctx, "d", func() {}))
+	require.Nil(t, client.Notify(ctx, "e", map[string]int(nil)))
+	require.Nil(t, client.Notify(ctx, "f", []int(nil)))
+
+	assert.Equal(t, []string{
+		`{"jsonrpc":"2.0","method":"a","params":[]}`,
+		`{"jsonrpc":"2.0","method":"b","params":{}}`,
+		`{"jsonrpc":"2.0","method":"c"}`,
+		`{"jsonrpc":"2.0","method":"e"}`,
+		`{"jsonrpc":"2.0","method":"f"}`,
+	}, bodies)
+}
+
+func TestJSONRPCIDKey(t *testing.T) {
+	//key - response id
+	//val - expected key
+	testData := map[string]string{
+		`1`:                    "1",
+		`1.0`:                  "1",
+		` 1e0 `:                "1",
+		`18446744073709551615`: "18446744073709551615",
+		`1.5`:                  "1.5",
+		`"1"`:                  `"1"`,
+		`null`:                 "null",
+	}
+
+	for id, result := range testData {
+		assert.Equal(t, result, jsonRPCIDKey(json.RawMessage(id)), id)
+	}
+}
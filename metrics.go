@@ -0,0 +1,208 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//DefaultLatencyBuckets default upper bounds in seconds
+//for request latency histogram
+var DefaultLatencyBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+//APIMetrics collects APIClient request counts, latency histograms
+//and in-flight gauges labelled by method, host and status class.
+//APIMetrics implements http.Handler and writes collected values
+//in the Prometheus text exposition format.
+type APIMetrics struct {
+	namespace string
+	buckets   []float64
+
+	mu        sync.Mutex
+	requests  map[requestLabels]*latencyHistogram
+	inFlights map[inFlightLabels]int64
+}
+
+type requestLabels struct {
+	method string
+	host   string
+	status string
+}
+
+type inFlightLabels struct {
+	method string
+	host   string
+}
+
+type latencyHistogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+//NewAPIMetrics create new metrics collector.
+//Metric names are prefixed by namespace, "apiclient" when empty.
+//When buckets is empty DefaultLatencyBuckets is used.
+func NewAPIMetrics(namespace string, buckets []float64) *APIMetrics {
+	if len(namespace) == 0 {
+		namespace = "apiclient"
+	}
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	return &APIMetrics{
+		namespace: namespace,
+		buckets:   sorted,
+		requests:  make(map[requestLabels]*latencyHistogram),
+		inFlights: make(map[inFlightLabels]int64),
+	}
+}
+
+//begin register request as in flight and return func
+//which must be called when request is finished.
+//Safe to call on nil collector.
+func (m *APIMetrics) begin(req *http.Request) func(res *http.Response, err error) {
+	if m == nil {
+		return func(*http.Response, error) {}
+	}
+
+	flight := inFlightLabels{method: req.Method, host: req.URL.Host}
+	m.mu.Lock()
+	m.inFlights[flight]++
+	m.mu.Unlock()
+
+	start := time.Now()
+	return func(res *http.Response, err error) {
+		m.observe(flight, statusClass(res, err), time.Since(start))
+	}
+}
+
+func (m *APIMetrics) observe(flight inFlightLabels, status string, elapsed time.Duration) {
+	labels := requestLabels{method: flight.method, host: flight.host, status: status}
+	seconds := elapsed.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlights[flight]--
+
+	h, ok := m.requests[labels]
+	if !ok {
+		h = &latencyHistogram{counts: make([]uint64, len(m.buckets))}
+		m.requests[labels] = h
+	}
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+//statusClass return status label value like "2xx", "4xx"
+//or "error" when response was not received
+func statusClass(res *http.Response, err error) string {
+	if res == nil {
+		return "error"
+	}
+	return strconv.Itoa(res.StatusCode/100) + "xx"
+}
+
+//ServeHTTP write metrics in the Prometheus text exposition format
+func (m *APIMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	buf := bufio.NewWriter(w)
+	m.writeTo(buf)
+	buf.Flush()
+}
+
+func (m *APIMetrics) writeTo(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requestKeys := make([]requestLabels, 0, len(m.requests))
+	for labels := range m.requests {
+		requestKeys = append(requestKeys, labels)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		a, b := requestKeys[i], requestKeys[j]
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		if a.host != b.host {
+			return a.host < b.host
+		}
+		return a.status < b.status
+	})
+
+	flightKeys := make([]inFlightLabels, 0, len(m.inFlights))
+	for labels := range m.inFlights {
+		flightKeys = append(flightKeys, labels)
+	}
+	sort.Slice(flightKeys, func(i, j int) bool {
+		a, b := flightKeys[i], flightKeys[j]
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.host < b.host
+	})
+
+	name := m.namespace + "_requests_total"
+	fmt.Fprintf(w, "# HELP %s Total number of API client requests.\n", name)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	for _, labels := range requestKeys {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, labels.String(), m.requests[labels].count)
+	}
+
+	name = m.namespace + "_request_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s API client request latency in seconds.\n", name)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	for _, labels := range requestKeys {
+		h := m.requests[labels]
+		for i, bound := range m.buckets {
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n",
+				name, labels.String(), formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels.String(), h.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels.String(), formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels.String(), h.count)
+	}
+
+	name = m.namespace + "_requests_in_flight"
+	fmt.Fprintf(w, "# HELP %s Number of API client requests in flight.\n", name)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	for _, labels := range flightKeys {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, labels.String(), m.inFlights[labels])
+	}
+}
+
+func (l requestLabels) String() string {
+	return fmt.Sprintf("method=%s,host=%s,status=%s",
+		quoteLabel(l.method), quoteLabel(l.host), quoteLabel(l.status))
+}
+
+func (l inFlightLabels) String() string {
+	return fmt.Sprintf("method=%s,host=%s", quoteLabel(l.method), quoteLabel(l.host))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
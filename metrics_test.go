@@ -0,0 +1,62 @@
+package util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIMetricsServeHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	metrics := NewAPIMetrics("test", []float64{1, 0.5})
+	client := NewAPIClient(1000).WithMetrics(metrics)
+
+	post := &TestPost{}
+	require.Nil(t, client.GetJSON(server.URL+"/posts/1", post))
+	require.Nil(t, client.PostJSON(server.URL+"/posts", post, post))
+	require.NotNil(t, client.GetJSON(server.URL+"/missing", post))
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+	text := string(body)
+
+	u, _ := url.Parse(server.URL)
+	host := u.Host
+	require.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain"))
+	require.Contains(t, text, "# TYPE test_requests_total counter\n")
+	require.Contains(t, text, `test_requests_total{method="GET",host="`+host+`",status="2xx"} 1`)
+	require.Contains(t, text, `test_requests_total{method="GET",host="`+host+`",status="4xx"} 1`)
+	require.Contains(t, text, `test_requests_total{method="POST",host="`+host+`",status="2xx"} 1`)
+	require.Contains(t, text, `test_request_duration_seconds_bucket{method="GET",host="`+host+`",status="2xx",le="0.5"} 1`)
+	require.Contains(t, text, `test_request_duration_seconds_bucket{method="GET",host="`+host+`",status="2xx",le="+Inf"} 1`)
+	require.Contains(t, text, `test_requests_in_flight{method="GET",host="`+host+`"} 0`)
+	require.True(t,
+		strings.Index(text, `le="0.5"`) < strings.Index(text, `le="1"`))
+}
+
+func TestAPIMetricsTransportError(t *testing.T) {
+	metrics := NewAPIMetrics("", nil)
+	client := NewAPIClient(150).WithMetrics(metrics)
+
+	post := &TestPost{}
+	require.NotNil(t, client.GetJSON("http://127.0.0.1:1/", post))
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, rec.Body.String(),
+		`apiclient_requests_total{method="GET",host="127.0.0.1:1",status="error"} 1`)
+}
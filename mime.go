@@ -1,6 +1,10 @@
 package util
 
-import "path/filepath"
+import (
+	"net/url"
+	"path"
+	"strings"
+)
 
 const (
 	//MimeVideoMp4 video/mp4
@@ -27,10 +31,84 @@ const (
 	//MimeImageGif "image/gif"
 	MimeImageGif = "image/gif"
 
+	//MimeImageWebp "image/webp"
+	MimeImageWebp = "image/webp"
+
 	//MimeTextHTML "text/html"
 	MimeTextHTML = "text/html"
+
+	//MimeAudioMpeg "audio/mpeg"
+	MimeAudioMpeg = "audio/mpeg"
+
+	//MimeAudioAac "audio/aac"
+	MimeAudioAac = "audio/aac"
+
+	//MimeAudioMp4 "audio/mp4"
+	MimeAudioMp4 = "audio/mp4"
+
+	//MimeAudioOgg "audio/ogg"
+	MimeAudioOgg = "audio/ogg"
+
+	//MimeAudioOpus "audio/opus"
+	MimeAudioOpus = "audio/opus"
+
+	//MimeAudioFlac "audio/flac"
+	MimeAudioFlac = "audio/flac"
+
+	//MimeAudioWav "audio/wav"
+	MimeAudioWav = "audio/wav"
+
+	//MimeApplicationPdf "application/pdf"
+	MimeApplicationPdf = "application/pdf"
+
+	//MimeApplicationMsword "application/msword"
+	MimeApplicationMsword = "application/msword"
+
+	//MimeApplicationDocx "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	MimeApplicationDocx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+	//MimeApplicationExcel "application/vnd.ms-excel"
+	MimeApplicationExcel = "application/vnd.ms-excel"
+
+	//MimeApplicationXlsx "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	MimeApplicationXlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	//MimeApplicationJSON "application/json"
+	MimeApplicationJSON = "application/json"
+
+	//MimeTextCSV "text/csv"
+	MimeTextCSV = "text/csv"
+
+	//MimeTextPlain "text/plain"
+	MimeTextPlain = "text/plain"
+
+	//MimeApplicationZip "application/zip"
+	MimeApplicationZip = "application/zip"
+
+	//MimeApplicationGzip "application/gzip"
+	MimeApplicationGzip = "application/gzip"
+
+	//MimeApplicationTar "application/x-tar"
+	MimeApplicationTar = "application/x-tar"
+
+	//MimeApplication7z "application/x-7z-compressed"
+	MimeApplication7z = "application/x-7z-compressed"
+
+	//MimeFontWoff "font/woff"
+	MimeFontWoff = "font/woff"
+
+	//MimeFontWoff2 "font/woff2"
+	MimeFontWoff2 = "font/woff2"
+
+	//MimeFontTtf "font/ttf"
+	MimeFontTtf = "font/ttf"
+
+	//MimeFontOtf "font/otf"
+	MimeFontOtf = "font/otf"
 )
 
+//videoMimes, imageMimes, htmlMimes, audioMimes, documentMimes,
+//archiveMimes and fontMimes are preloaded into DefaultMimeRegistry
 var videoMimes = map[string]string{
 	".mp4":  MimeVideoMp4,
 	".m4v":  MimeVideoMp4,
@@ -55,6 +133,7 @@ var imageMimes = map[string]string{
 	".png":  MimeImagePng,
 	".jpeg": MimeImageJpeg,
 	".jpg":  MimeImageJpeg,
+	".webp": MimeImageWebp,
 }
 
 var htmlMimes = map[string]string{
@@ -62,28 +141,163 @@ var htmlMimes = map[string]string{
 	".htm":  MimeTextHTML,
 }
 
+var audioMimes = map[string]string{
+	".mp3":  MimeAudioMpeg,
+	".aac":  MimeAudioAac,
+	".m4a":  MimeAudioMp4,
+	".ogg":  MimeAudioOgg,
+	".oga":  MimeAudioOgg,
+	".opus": MimeAudioOpus,
+	".flac": MimeAudioFlac,
+	".wav":  MimeAudioWav,
+}
+
+var documentMimes = map[string]string{
+	".pdf":  MimeApplicationPdf,
+	".doc":  MimeApplicationMsword,
+	".docx": MimeApplicationDocx,
+	".xls":  MimeApplicationExcel,
+	".xlsx": MimeApplicationXlsx,
+	".csv":  MimeTextCSV,
+	".json": MimeApplicationJSON,
+	".txt":  MimeTextPlain,
+}
+
+var archiveMimes = map[string]string{
+	".zip": MimeApplicationZip,
+	".gz":  MimeApplicationGzip,
+	".tgz": MimeApplicationGzip,
+	".tar": MimeApplicationTar,
+	".7z":  MimeApplication7z,
+}
+
+var fontMimes = map[string]string{
+	".woff":  MimeFontWoff,
+	".woff2": MimeFontWoff2,
+	".ttf":   MimeFontTtf,
+	".otf":   MimeFontOtf,
+}
+
+//canonicalMimeExtensions preferred extensions of mime types
+//which have several extensions
+var canonicalMimeExtensions = map[string]string{
+	MimeVideoMp4:        ".mp4",
+	MimeVideo3gpp:       ".3gp",
+	MimeVideoMpeg:       ".mpeg",
+	MimeImageJpeg:       ".jpg",
+	MimeTextHTML:        ".html",
+	MimeAudioOgg:        ".ogg",
+	MimeApplicationGzip: ".gz",
+}
+
+//mimeAliases non-standard or legacy names of mime types
+var mimeAliases = map[string]string{
+	"image/jpg":                    MimeImageJpeg,
+	"image/pjpeg":                  MimeImageJpeg,
+	"image/x-png":                  MimeImagePng,
+	"video/x-m4v":                  MimeVideoMp4,
+	"video/3gp":                    MimeVideo3gpp,
+	"video/flv":                    MimeVideoXflv,
+	"audio/mp3":                    MimeAudioMpeg,
+	"audio/mpeg3":                  MimeAudioMpeg,
+	"audio/x-mpeg-3":               MimeAudioMpeg,
+	"audio/x-aac":                  MimeAudioAac,
+	"audio/x-m4a":                  MimeAudioMp4,
+	"audio/x-wav":                  MimeAudioWav,
+	"audio/wave":                   MimeAudioWav,
+	"audio/vnd.wave":               MimeAudioWav,
+	"audio/x-flac":                 MimeAudioFlac,
+	"application/x-pdf":            MimeApplicationPdf,
+	"text/x-csv":                   MimeTextCSV,
+	"text/json":                    MimeApplicationJSON,
+	"application/x-zip-compressed": MimeApplicationZip,
+	"application/x-gzip":           MimeApplicationGzip,
+	"application/x-font-woff":      MimeFontWoff,
+	"application/font-woff":        MimeFontWoff,
+	"application/x-font-ttf":       MimeFontTtf,
+	"application/x-font-otf":       MimeFontOtf,
+}
+
 //GetVideoMime return video mime type by source url
 //If file in url has not supported extension return empty string
 func GetVideoMime(sourceURL string) string {
-	return getMime(sourceURL, videoMimes)
+	return DefaultMimeRegistry.LookupURL(sourceURL, MimeCategoryVideo)
 }
 
 //GetImageMime return image mime type by source url
 //If file in url has not supported extension return empty string
 func GetImageMime(sourceURL string) string {
-	return getMime(sourceURL, imageMimes)
+	return DefaultMimeRegistry.LookupURL(sourceURL, MimeCategoryImage)
 }
 
 //GetHTMLMime return html mime type by source url
 //If file in url has not supported extension return empty string
 func GetHTMLMime(sourceURL string) string {
-	return getMime(sourceURL, htmlMimes)
+	return DefaultMimeRegistry.LookupURL(sourceURL, MimeCategoryHTML)
+}
+
+//GetAudioMime return audio mime type by source url
+//If file in url has not supported extension return empty string
+func GetAudioMime(sourceURL string) string {
+	return DefaultMimeRegistry.LookupURL(sourceURL, MimeCategoryAudio)
+}
+
+//GetDocumentMime return document mime type by source url
+//If file in url has not supported extension return empty string
+func GetDocumentMime(sourceURL string) string {
+	return DefaultMimeRegistry.LookupURL(sourceURL, MimeCategoryDocument)
 }
 
-func getMime(sourceURL string, mimes map[string]string) string {
-	ext := filepath.Ext(sourceURL)
-	mime, _ := mimes[ext]
-	return mime
+//GetArchiveMime return archive mime type by source url
+//If file in url has not supported extension return empty string
+func GetArchiveMime(sourceURL string) string {
+	return DefaultMimeRegistry.LookupURL(sourceURL, MimeCategoryArchive)
+}
+
+//GetFontMime return font mime type by source url
+//If file in url has not supported extension return empty string
+func GetFontMime(sourceURL string) string {
+	return DefaultMimeRegistry.LookupURL(sourceURL, MimeCategoryFont)
+}
+
+//GetMime return mime type and its category by source url
+//If file in url has not supported extension return empty strings
+func GetMime(sourceURL string) (string, MimeCategory) {
+	t, _ := DefaultMimeRegistry.lookupURL(sourceURL, "")
+	return t.Mime, t.Category
+}
+
+//DefaultMimeQueryParams well-known query parameters with file extension,
+//they are consulted only after MimeRegistry.SetQueryParams
+var DefaultMimeQueryParams = []string{"format", "ext"}
+
+//urlExtension return lower case extension of url path.
+//Query and fragment are never part of path extension.
+//When path has no extension it is taken from first non-empty
+//query parameter of params.
+func urlExtension(sourceURL string, params []string) string {
+	sourceURL = strings.TrimSpace(sourceURL)
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		//keep extension of urls with invalid escaping, e.g. %zz.mp4
+		if i := strings.IndexAny(sourceURL, "?#"); i >= 0 {
+			sourceURL = sourceURL[:i]
+		}
+		return strings.ToLower(path.Ext(sourceURL))
+	}
+
+	if ext := path.Ext(u.Path); len(ext) > 0 {
+		return strings.ToLower(ext)
+	}
+
+	query := u.Query()
+	for _, param := range params {
+		value := strings.TrimPrefix(strings.TrimSpace(query.Get(param)), ".")
+		if len(value) > 0 {
+			return "." + strings.ToLower(value)
+		}
+	}
+	return ""
 }
 
 //IsValidVideoURL check is string is valid video path or not
@@ -100,3 +314,23 @@ func IsValidImageURL(rawurl string) bool {
 func IsValidHTMLURL(rawurl string) bool {
 	return IsValidURL(rawurl) && len(GetHTMLMime(rawurl)) > 0
 }
+
+//IsValidAudioURL check is string is valid audio path or not
+func IsValidAudioURL(rawurl string) bool {
+	return IsValidURL(rawurl) && len(GetAudioMime(rawurl)) > 0
+}
+
+//IsValidDocumentURL check is string is valid document path or not
+func IsValidDocumentURL(rawurl string) bool {
+	return IsValidURL(rawurl) && len(GetDocumentMime(rawurl)) > 0
+}
+
+//IsValidArchiveURL check is string is valid archive path or not
+func IsValidArchiveURL(rawurl string) bool {
+	return IsValidURL(rawurl) && len(GetArchiveMime(rawurl)) > 0
+}
+
+//IsValidFontURL check is string is valid font path or not
+func IsValidFontURL(rawurl string) bool {
+	return IsValidURL(rawurl) && len(GetFontMime(rawurl)) > 0
+}
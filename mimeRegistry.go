@@ -0,0 +1,345 @@
+package util
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//MimeCategory is family of mime types, e.g. video or image
+type MimeCategory string
+
+const (
+	//MimeCategoryVideo video files
+	MimeCategoryVideo MimeCategory = "video"
+
+	//MimeCategoryImage image files
+	MimeCategoryImage MimeCategory = "image"
+
+	//MimeCategoryHTML html pages
+	MimeCategoryHTML MimeCategory = "html"
+
+	//MimeCategoryAudio audio files
+	MimeCategoryAudio MimeCategory = "audio"
+
+	//MimeCategoryDocument documents and data files
+	MimeCategoryDocument MimeCategory = "document"
+
+	//MimeCategoryArchive archives and compressed files
+	MimeCategoryArchive MimeCategory = "archive"
+
+	//MimeCategoryFont font files
+	MimeCategoryFont MimeCategory = "font"
+
+	//MimeCategoryOther types loaded from mime.types files
+	//which do not belong to other categories
+	MimeCategoryOther MimeCategory = "other"
+)
+
+//ErrInvalidMimeRegistration returned when extension or mime is empty
+var ErrInvalidMimeRegistration = errors.New("mime registration needs extension and mime type")
+
+//ErrMimeExtensionNotRegistered returned when canonical extension
+//is not registered for mime type
+var ErrMimeExtensionNotRegistered = errors.New("extension is not registered for mime type")
+
+//MimeType is mime type registered for file extension
+type MimeType struct {
+	Mime     string
+	Category MimeCategory
+}
+
+//MimeRegistry maps file extensions to mime types.
+//It is safe for concurrent use.
+type MimeRegistry struct {
+	mu    sync.RWMutex
+	types map[string]MimeType
+
+	//aliases maps alternative mime names to registered ones
+	aliases map[string]string
+
+	//canonical preferred extension of mime type
+	canonical map[string]string
+
+	//queryParams may contain extension of url without path extension,
+	//nil when query is not consulted
+	queryParams []string
+}
+
+//DefaultMimeRegistry is used by GetVideoMime, GetImageMime
+//and other package level lookups. Types registered in it
+//are recognized by them too.
+var DefaultMimeRegistry = newDefaultMimeRegistry()
+
+func newDefaultMimeRegistry() *MimeRegistry {
+	r := NewMimeRegistry()
+	families := map[MimeCategory]map[string]string{
+		MimeCategoryVideo:    videoMimes,
+		MimeCategoryImage:    imageMimes,
+		MimeCategoryHTML:     htmlMimes,
+		MimeCategoryAudio:    audioMimes,
+		MimeCategoryDocument: documentMimes,
+		MimeCategoryArchive:  archiveMimes,
+		MimeCategoryFont:     fontMimes,
+	}
+	//sorted registration keeps canonical extensions deterministic
+	for category, mimes := range families {
+		exts := make([]string, 0, len(mimes))
+		for ext := range mimes {
+			exts = append(exts, ext)
+		}
+		sort.Strings(exts)
+		for _, ext := range exts {
+			r.Register(ext, mimes[ext], category)
+		}
+	}
+	for mime, ext := range canonicalMimeExtensions {
+		r.SetCanonicalExtension(mime, ext)
+	}
+	for alias, mime := range mimeAliases {
+		r.RegisterAlias(alias, mime)
+	}
+	return r
+}
+
+//NewMimeRegistry create empty registry
+func NewMimeRegistry() *MimeRegistry {
+	return &MimeRegistry{
+		types:     make(map[string]MimeType),
+		aliases:   make(map[string]string),
+		canonical: make(map[string]string),
+	}
+}
+
+//normalizeMime return lower case mime type without parameters,
+//e.g. "text/html" for "Text/HTML; charset=utf-8"
+func normalizeMime(mime string) string {
+	if i := strings.IndexByte(mime, ';'); i >= 0 {
+		mime = mime[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mime))
+}
+
+//normalizeExt return lower case extension with leading dot
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if len(ext) > 0 && ext[0] != '.' {
+		ext = "." + ext
+	}
+	return ext
+}
+
+//Register map extension to mime type of category.
+//Extension is case insensitive, leading dot is optional.
+//Previous registration of extension is replaced.
+func (r *MimeRegistry) Register(ext, mime string, category MimeCategory) error {
+	ext = normalizeExt(ext)
+	mime = strings.TrimSpace(mime)
+	if len(ext) < 2 || len(mime) == 0 {
+		return ErrInvalidMimeRegistration
+	}
+
+	r.mu.Lock()
+	r.register(ext, mime, category)
+	r.mu.Unlock()
+	return nil
+}
+
+//register set type of normalized extension, r.mu must be locked
+func (r *MimeRegistry) register(ext, mime string, category MimeCategory) {
+	r.types[ext] = MimeType{Mime: mime, Category: category}
+	if _, ok := r.canonical[normalizeMime(mime)]; !ok {
+		r.canonical[normalizeMime(mime)] = ext
+	}
+}
+
+//RegisterAlias make reverse lookups of alias return
+//extensions of mime, e.g. image/jpg for image/jpeg
+func (r *MimeRegistry) RegisterAlias(alias, mime string) error {
+	alias, mime = normalizeMime(alias), normalizeMime(mime)
+	if len(alias) == 0 || len(mime) == 0 {
+		return ErrInvalidMimeRegistration
+	}
+
+	r.mu.Lock()
+	r.aliases[alias] = mime
+	r.mu.Unlock()
+	return nil
+}
+
+//SetCanonicalExtension set extension returned by CanonicalExtension
+//for mime. Extension must be registered for mime.
+//By default it is first extension registered for mime.
+func (r *MimeRegistry) SetCanonicalExtension(mime, ext string) error {
+	mime, ext = normalizeMime(mime), normalizeExt(ext)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.types[ext]
+	if !ok || normalizeMime(t.Mime) != mime {
+		return ErrMimeExtensionNotRegistered
+	}
+	r.canonical[mime] = ext
+	return nil
+}
+
+//ExtensionsForMime return extensions registered for mime type.
+//Parameters and case of mime are ignored, aliases are resolved.
+//Canonical extension is first, others are sorted.
+func (r *MimeRegistry) ExtensionsForMime(mime string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.extensionsForMime(r.resolveAlias(normalizeMime(mime)))
+}
+
+//extensionsForMime return extensions of normalized mime, r.mu must be locked
+func (r *MimeRegistry) extensionsForMime(mime string) []string {
+	canonical := r.canonicalExtension(mime)
+	if len(canonical) == 0 {
+		return nil
+	}
+
+	exts := []string{canonical}
+	var others []string
+	for ext, t := range r.types {
+		if ext != canonical && normalizeMime(t.Mime) == mime {
+			others = append(others, ext)
+		}
+	}
+	sort.Strings(others)
+	return append(exts, others...)
+}
+
+//CanonicalExtension return preferred extension of mime type
+//or empty string when mime type is not registered
+func (r *MimeRegistry) CanonicalExtension(mime string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.canonicalExtension(r.resolveAlias(normalizeMime(mime)))
+}
+
+func (r *MimeRegistry) resolveAlias(mime string) string {
+	if target, ok := r.aliases[mime]; ok {
+		return target
+	}
+	return mime
+}
+
+//canonicalExtension return preferred extension of normalized mime,
+//first sorted extension when preferred one was registered for other mime
+func (r *MimeRegistry) canonicalExtension(mime string) string {
+	if ext, ok := r.canonical[mime]; ok {
+		if t, ok := r.types[ext]; ok && normalizeMime(t.Mime) == mime {
+			return ext
+		}
+	}
+
+	canonical := ""
+	for ext, t := range r.types {
+		if normalizeMime(t.Mime) == mime && (len(canonical) == 0 || ext < canonical) {
+			canonical = ext
+		}
+	}
+	return canonical
+}
+
+//Lookup return mime type registered for extension
+func (r *MimeRegistry) Lookup(ext string) (MimeType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.types[normalizeExt(ext)]
+	return t, ok
+}
+
+//LookupURL return mime type of file in url with category.
+//Empty category matches any category. If file in url has
+//not registered extension return empty string.
+func (r *MimeRegistry) LookupURL(sourceURL string, category MimeCategory) string {
+	t, _ := r.lookupURL(sourceURL, category)
+	return t.Mime
+}
+
+func (r *MimeRegistry) lookupURL(sourceURL string, category MimeCategory) (MimeType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.types[urlExtension(sourceURL, r.queryParams)]
+	if !ok || (len(category) > 0 && t.Category != category) {
+		return MimeType{}, false
+	}
+	return t, true
+}
+
+//SetQueryParams make url lookups take extension from first non-empty
+//query parameter of params when url path has no extension,
+//e.g. /download?format=mp4 for DefaultMimeQueryParams.
+//Query is not consulted by default, call without params disables it.
+func (r *MimeRegistry) SetQueryParams(params ...string) {
+	r.mu.Lock()
+	r.queryParams = append([]string(nil), params...)
+	r.mu.Unlock()
+}
+
+//Extensions return sorted extensions registered with category
+func (r *MimeRegistry) Extensions(category MimeCategory) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var exts []string
+	for ext, t := range r.types {
+		if t.Category == category {
+			exts = append(exts, ext)
+		}
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+//Categories return sorted categories which have registered extensions
+func (r *MimeRegistry) Categories() []MimeCategory {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[MimeCategory]bool)
+	var categories []MimeCategory
+	for _, t := range r.types {
+		if !seen[t.Category] {
+			seen[t.Category] = true
+			categories = append(categories, t.Category)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i] < categories[j] })
+	return categories
+}
+
+//Clone return independent copy of registry
+func (r *MimeRegistry) Clone() *MimeRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clone := NewMimeRegistry()
+	for ext, t := range r.types {
+		clone.types[ext] = t
+	}
+	for alias, mime := range r.aliases {
+		clone.aliases[alias] = mime
+	}
+	for mime, ext := range r.canonical {
+		clone.canonical[mime] = ext
+	}
+	clone.queryParams = append([]string(nil), r.queryParams...)
+	return clone
+}
+
+//ExtensionsForMime return extensions of mime type in DefaultMimeRegistry,
+//canonical extension is first
+func ExtensionsForMime(mime string) []string {
+	return DefaultMimeRegistry.ExtensionsForMime(mime)
+}
+
+//CanonicalExtension return preferred extension of mime type
+//in DefaultMimeRegistry, e.g. ".jpg" for "image/jpeg"
+func CanonicalExtension(mime string) string {
+	return DefaultMimeRegistry.CanonicalExtension(mime)
+}
@@ -0,0 +1,131 @@
+package util
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMimeRegistry(t *testing.T) {
+	r := NewMimeRegistry()
+	require.Nil(t, r.Register("MKV", "video/x-matroska", MimeCategoryVideo))
+	require.Nil(t, r.Register(".avif", "image/avif", MimeCategoryImage))
+	require.Nil(t, r.Register(".mov", "video/quicktime", MimeCategoryVideo))
+	assert.Equal(t, ErrInvalidMimeRegistration, r.Register("", "video/x", MimeCategoryVideo))
+	assert.Equal(t, ErrInvalidMimeRegistration, r.Register(".", "video/x", MimeCategoryVideo))
+	assert.Equal(t, ErrInvalidMimeRegistration, r.Register(".x", " ", MimeCategoryVideo))
+
+	mime, ok := r.Lookup(".Mkv")
+	assert.True(t, ok)
+	assert.Equal(t, MimeType{Mime: "video/x-matroska", Category: MimeCategoryVideo}, mime)
+	_, ok = r.Lookup(".mp4")
+	assert.False(t, ok)
+
+	assert.Equal(t, "image/avif", r.LookupURL("https://cdn.example.com/a.avif?w=100", MimeCategoryImage))
+	assert.Equal(t, "", r.LookupURL("https://cdn.example.com/a.avif", MimeCategoryVideo))
+	assert.Equal(t, "image/avif", r.LookupURL("https://cdn.example.com/a.avif", ""))
+
+	assert.Equal(t, []string{".mkv", ".mov"}, r.Extensions(MimeCategoryVideo))
+	assert.Equal(t, []MimeCategory{MimeCategoryImage, MimeCategoryVideo}, r.Categories())
+
+	//registration replaces previous one
+	require.Nil(t, r.Register("mov", "video/mp4", MimeCategoryVideo))
+	mime, _ = r.Lookup("mov")
+	assert.Equal(t, "video/mp4", mime.Mime)
+
+	clone := r.Clone()
+	require.Nil(t, clone.Register(".heic", "image/heic", MimeCategoryImage))
+	_, ok = r.Lookup(".heic")
+	assert.False(t, ok)
+}
+
+func TestDefaultMimeRegistry(t *testing.T) {
+	assert.Equal(t, []string{".gif", ".jpeg", ".jpg", ".png", ".webp"}, DefaultMimeRegistry.Extensions(MimeCategoryImage))
+	assert.Equal(t, []string{".htm", ".html"}, DefaultMimeRegistry.Extensions(MimeCategoryHTML))
+
+	assert.False(t, IsValidVideoURL("http://test.com/video.mkv"))
+	require.Nil(t, DefaultMimeRegistry.Register(".mkv", "video/x-matroska", MimeCategoryVideo))
+	defer func() {
+		DefaultMimeRegistry = newDefaultMimeRegistry()
+	}()
+	assert.Equal(t, "video/x-matroska", GetVideoMime("http://test.com/video.MKV"))
+	assert.True(t, IsValidVideoURL("http://test.com/video.mkv"))
+	assert.Equal(t, "", GetImageMime("http://test.com/video.mkv"))
+}
+
+func TestMimeRegistryConcurrentUse(t *testing.T) {
+	r := NewMimeRegistry()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		ext := string(rune('a' + i))
+		go func() {
+			defer wg.Done()
+			r.Register(ext, "application/"+ext, "custom")
+		}()
+		go func() {
+			defer wg.Done()
+			r.Lookup(ext)
+			r.Extensions("custom")
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, r.Extensions("custom"), 10)
+}
+
+func TestExtensionsForMime(t *testing.T) {
+	//key - mime type
+	//val - expexted extensions
+	testData := map[string][]string{
+		MimeImageJpeg:              {".jpg", ".jpeg"},
+		"IMAGE/JPEG; q=0.9":        {".jpg", ".jpeg"},
+		"image/jpg":                {".jpg", ".jpeg"},
+		"video/x-m4v":              {".mp4", ".m4v", ".mp4v"},
+		MimeVideo3gpp:              {".3gp", ".3gpp"},
+		"text/html; charset=utf-8": {".html", ".htm"},
+		MimeApplicationGzip:        {".gz", ".tgz"},
+		"application/x-gzip":       {".gz", ".tgz"},
+		MimeApplicationPdf:         {".pdf"},
+		"application/octet-stream": nil,
+		"":                         nil,
+	}
+
+	for mime, result := range testData {
+		assert.Equal(t, result, ExtensionsForMime(mime), mime)
+	}
+
+	assert.Equal(t, ".mpeg", CanonicalExtension(MimeVideoMpeg))
+	assert.Equal(t, ".mp3", CanonicalExtension("audio/mp3"))
+	assert.Equal(t, ".wav", CanonicalExtension("audio/x-wav"))
+	assert.Equal(t, "", CanonicalExtension("application/x-unknown"))
+}
+
+func TestMimeRegistryCanonicalExtension(t *testing.T) {
+	r := NewMimeRegistry()
+	require.Nil(t, r.Register(".jpeg", MimeImageJpeg, MimeCategoryImage))
+	require.Nil(t, r.Register(".jpg", MimeImageJpeg, MimeCategoryImage))
+	require.Nil(t, r.Register(".jpe", MimeImageJpeg, MimeCategoryImage))
+
+	//first registered extension is canonical by default
+	assert.Equal(t, ".jpeg", r.CanonicalExtension(MimeImageJpeg))
+	assert.Equal(t, []string{".jpeg", ".jpe", ".jpg"}, r.ExtensionsForMime(MimeImageJpeg))
+
+	require.Nil(t, r.SetCanonicalExtension(MimeImageJpeg, "JPG"))
+	assert.Equal(t, ".jpg", r.CanonicalExtension(MimeImageJpeg))
+	assert.Equal(t, ErrMimeExtensionNotRegistered, r.SetCanonicalExtension(MimeImageJpeg, ".png"))
+
+	//canonical extension moved to other mime
+	require.Nil(t, r.Register(".jpg", "image/x-custom", MimeCategoryImage))
+	assert.Equal(t, ".jpe", r.CanonicalExtension(MimeImageJpeg))
+	assert.Equal(t, ".jpg", r.CanonicalExtension("image/x-custom"))
+
+	assert.Equal(t, "", r.CanonicalExtension("image/jpg"))
+	require.Nil(t, r.RegisterAlias("Image/JPG", MimeImageJpeg))
+	assert.Equal(t, ".jpe", r.CanonicalExtension("image/jpg"))
+	assert.Equal(t, ErrInvalidMimeRegistration, r.RegisterAlias("", MimeImageJpeg))
+
+	clone := r.Clone()
+	assert.Equal(t, []string{".jpe", ".jpeg"}, clone.ExtensionsForMime("image/jpg"))
+}
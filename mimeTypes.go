@@ -0,0 +1,295 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+//SystemMimeTypesFiles are loaded by LoadSystemMimeTypes in this order
+var SystemMimeTypesFiles = []string{
+	"/etc/mime.types",
+	"/etc/apache2/mime.types",
+	"/etc/apache/mime.types",
+	"/etc/httpd/conf/mime.types",
+}
+
+//MimeMergePriority defines which type wins when loaded file
+//maps extension which is already registered
+type MimeMergePriority int
+
+const (
+	//MimePreferExisting keep registered types, only new extensions are added
+	MimePreferExisting MimeMergePriority = iota
+
+	//MimePreferLoaded replace registered types by loaded ones
+	MimePreferLoaded
+)
+
+//MimeTypesEntry is mime type with its extensions from mime.types file
+type MimeTypesEntry struct {
+	Mime       string
+	Extensions []string
+
+	//Line number of entry in file, starting from 1
+	Line int
+}
+
+//MimeTypesError returned when mime.types file has invalid syntax
+type MimeTypesError struct {
+	//File name, empty when reader was parsed
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *MimeTypesError) Error() string {
+	file := e.File
+	if len(file) == 0 {
+		file = "mime.types"
+	}
+	return fmt.Sprintf("%s:%d: %s", file, e.Line, e.Msg)
+}
+
+//ParseMimeTypes parse file in Apache mime.types format:
+//
+//	# comment
+//	video/mp4	mp4 m4v
+//
+//or in nginx types format:
+//
+//	types {
+//	    video/mp4  mp4 m4v;
+//	}
+func ParseMimeTypes(r io.Reader) ([]MimeTypesEntry, error) {
+	p := &mimeTypesParser{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if err := p.parseLine(scanner.Text(), line); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if p.inBlock {
+		return nil, &MimeTypesError{Line: line, Msg: "missing closing }"}
+	}
+	if len(p.tokens) > 0 {
+		//only "types" waiting for { is left pending by parseLine
+		return nil, &MimeTypesError{Line: p.start, Msg: "missing { after types"}
+	}
+	return p.entries, nil
+}
+
+//mimeTypesParser collects tokens of entries, in nginx block
+//entry may span several lines until ';'
+type mimeTypesParser struct {
+	entries []MimeTypesEntry
+	inBlock bool
+
+	tokens []string
+	start  int
+}
+
+func (p *mimeTypesParser) parseLine(text string, line int) error {
+	if i := strings.IndexByte(text, '#'); i >= 0 {
+		text = text[:i]
+	}
+	for _, sep := range []string{"{", "}", ";"} {
+		text = strings.Replace(text, sep, " "+sep+" ", -1)
+	}
+
+	for _, token := range strings.Fields(text) {
+		switch token {
+		case "{":
+			if p.inBlock || len(p.tokens) != 1 || p.tokens[0] != "types" {
+				return &MimeTypesError{Line: line, Msg: "unexpected {"}
+			}
+			p.inBlock = true
+			p.tokens = nil
+		case "}":
+			if !p.inBlock {
+				return &MimeTypesError{Line: line, Msg: "unexpected }"}
+			}
+			if len(p.tokens) > 0 {
+				return &MimeTypesError{Line: p.start, Msg: "missing ; after entry"}
+			}
+			p.inBlock = false
+		case ";":
+			if !p.inBlock {
+				return &MimeTypesError{Line: line, Msg: "unexpected ; outside of types block"}
+			}
+			if err := p.finishEntry(); err != nil {
+				return err
+			}
+		default:
+			if len(p.tokens) == 0 {
+				p.start = line
+			}
+			p.tokens = append(p.tokens, token)
+		}
+	}
+
+	//in Apache format line is entry, "types" may be followed by { on next line
+	if !p.inBlock && !(len(p.tokens) == 1 && p.tokens[0] == "types") {
+		return p.finishEntry()
+	}
+	return nil
+}
+
+func (p *mimeTypesParser) finishEntry() error {
+	if len(p.tokens) == 0 {
+		return nil
+	}
+	mime, exts := p.tokens[0], p.tokens[1:]
+	p.tokens = nil
+
+	if !isValidMimeName(mime) {
+		return &MimeTypesError{Line: p.start, Msg: fmt.Sprintf("invalid mime type %q", mime)}
+	}
+	entry := MimeTypesEntry{Mime: strings.ToLower(mime), Line: p.start}
+	for _, ext := range exts {
+		normalized := normalizeExt(ext)
+		if len(normalized) < 2 || strings.ContainsAny(normalized, "/\\") {
+			return &MimeTypesError{Line: p.start, Msg: fmt.Sprintf("invalid extension %q", ext)}
+		}
+		entry.Extensions = append(entry.Extensions, normalized)
+	}
+	p.entries = append(p.entries, entry)
+	return nil
+}
+
+//isValidMimeName check type/subtype syntax without parameters
+func isValidMimeName(mime string) bool {
+	parts := strings.Split(mime, "/")
+	if len(parts) != 2 {
+		return false
+	}
+	for _, part := range parts {
+		if len(part) == 0 {
+			return false
+		}
+		for _, c := range part {
+			if c <= ' ' || c >= 0x7F || strings.ContainsRune(`()<>@,;:\"[]?=`, c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//LoadMimeTypes parse rd by ParseMimeTypes and register its types.
+//Registry is not changed when rd has errors. With MimePreferLoaded
+//first extension of type in file becomes its canonical extension.
+//Category of loaded type is taken from extensions already registered
+//for it, otherwise from top level type, MimeCategoryOther for unknown ones.
+func (r *MimeRegistry) LoadMimeTypes(rd io.Reader, priority MimeMergePriority) error {
+	entries, err := ParseMimeTypes(rd)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, entry := range entries {
+		category := r.mimeCategory(entry.Mime)
+		for _, ext := range entry.Extensions {
+			if _, ok := r.types[ext]; ok && priority == MimePreferExisting {
+				continue
+			}
+			r.register(ext, entry.Mime, category)
+		}
+		//first extension in file is canonical one
+		if priority == MimePreferLoaded && len(entry.Extensions) > 0 {
+			r.canonical[entry.Mime] = entry.Extensions[0]
+		}
+	}
+	return nil
+}
+
+//LoadMimeTypesFile load types from mime.types file at path
+func (r *MimeRegistry) LoadMimeTypesFile(path string, priority MimeMergePriority) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = r.LoadMimeTypes(file, priority)
+	if typesErr, ok := err.(*MimeTypesError); ok {
+		typesErr.File = path
+	}
+	return err
+}
+
+//LoadSystemMimeTypes load existing SystemMimeTypesFiles
+//with MimePreferExisting priority
+func (r *MimeRegistry) LoadSystemMimeTypes() error {
+	for _, path := range SystemMimeTypesFiles {
+		err := r.LoadMimeTypesFile(path, MimePreferExisting)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+var mimeTopLevelCategories = map[string]MimeCategory{
+	"video": MimeCategoryVideo,
+	"image": MimeCategoryImage,
+	"audio": MimeCategoryAudio,
+	"font":  MimeCategoryFont,
+}
+
+//mimeCategory return category of normalized mime, r.mu must be locked
+func (r *MimeRegistry) mimeCategory(mime string) MimeCategory {
+	mime = r.resolveAlias(mime)
+	for _, t := range r.types {
+		if normalizeMime(t.Mime) == mime {
+			return t.Category
+		}
+	}
+
+	if mime == MimeTextHTML {
+		return MimeCategoryHTML
+	}
+	if category, ok := mimeTopLevelCategories[strings.SplitN(mime, "/", 2)[0]]; ok {
+		return category
+	}
+	return MimeCategoryOther
+}
+
+//WriteMimeTypes write registered types in mime.types format
+//sorted by mime type, canonical extension of type is first
+func (r *MimeRegistry) WriteMimeTypes(w io.Writer) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var mimes []string
+	for _, t := range r.types {
+		mime := normalizeMime(t.Mime)
+		if !seen[mime] {
+			seen[mime] = true
+			mimes = append(mimes, mime)
+		}
+	}
+	sort.Strings(mimes)
+
+	bw := bufio.NewWriter(w)
+	for _, mime := range mimes {
+		exts := r.extensionsForMime(mime)
+		for i, ext := range exts {
+			exts[i] = strings.TrimPrefix(ext, ".")
+		}
+		fmt.Fprintf(bw, "%s\t%s\n", mime, strings.Join(exts, " "))
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,164 @@
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testApacheMimeTypes = `# This is a comment.
+application/x-bzip2			bz2 boz
+audio/x-matroska			mka
+
+video/x-matroska			mkv mk3d  # inline comment
+video/quicktime				qt mov
+image/png				png
+application/vnd.empty
+`
+
+const testNginxMimeTypes = `
+types {
+    text/html                                        html htm shtml;
+    image/avif                                       avif;
+    application/vnd.openxmlformats-officedocument.presentationml.presentation
+                                                     pptx;
+}
+`
+
+func TestParseMimeTypes(t *testing.T) {
+	entries, err := ParseMimeTypes(strings.NewReader(testApacheMimeTypes))
+	require.Nil(t, err)
+	assert.Equal(t, []MimeTypesEntry{
+		{Mime: "application/x-bzip2", Extensions: []string{".bz2", ".boz"}, Line: 2},
+		{Mime: "audio/x-matroska", Extensions: []string{".mka"}, Line: 3},
+		{Mime: "video/x-matroska", Extensions: []string{".mkv", ".mk3d"}, Line: 5},
+		{Mime: "video/quicktime", Extensions: []string{".qt", ".mov"}, Line: 6},
+		{Mime: "image/png", Extensions: []string{".png"}, Line: 7},
+		{Mime: "application/vnd.empty", Line: 8},
+	}, entries)
+
+	entries, err = ParseMimeTypes(strings.NewReader(testNginxMimeTypes))
+	require.Nil(t, err)
+	assert.Equal(t, []MimeTypesEntry{
+		{Mime: "text/html", Extensions: []string{".html", ".htm", ".shtml"}, Line: 3},
+		{Mime: "image/avif", Extensions: []string{".avif"}, Line: 4},
+		{Mime: "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+			Extensions: []string{".pptx"}, Line: 5},
+	}, entries)
+}
+
+func TestParseMimeTypesErrors(t *testing.T) {
+	//key - file content
+	//val - expexted error
+	testData := map[string]string{
+		"video/mp4 mp4\nvideo mp4":         `mime.types:2: invalid mime type "video"`,
+		"video/mp4 mp4\n\nvideo/mp4/x mp4": `mime.types:3: invalid mime type "video/mp4/x"`,
+		"text/plain txt dir/x":             `mime.types:1: invalid extension "dir/x"`,
+		"text/plain txt;":                  `mime.types:1: unexpected ; outside of types block`,
+		"}":                                `mime.types:1: unexpected }`,
+		"types {\n text/plain txt\n}":      `mime.types:2: missing ; after entry`,
+		"types {\n text/plain txt;\n":      `mime.types:2: missing closing }`,
+		"types {\n types {\n":              `mime.types:2: unexpected {`,
+		"text/plain txt\ntypes\n":          `mime.types:2: missing { after types`,
+	}
+
+	for content, result := range testData {
+		_, err := ParseMimeTypes(strings.NewReader(content))
+		require.IsType(t, &MimeTypesError{}, err, content)
+		assert.Equal(t, result, err.Error())
+	}
+}
+
+func TestLoadMimeTypes(t *testing.T) {
+	r := DefaultMimeRegistry.Clone()
+	require.Nil(t, r.Register(".qt", "video/x-custom", MimeCategoryVideo))
+	require.Nil(t, r.LoadMimeTypes(strings.NewReader(testApacheMimeTypes), MimePreferExisting))
+
+	mime, _ := r.Lookup(".mkv")
+	assert.Equal(t, MimeType{Mime: "video/x-matroska", Category: MimeCategoryVideo}, mime)
+	mime, _ = r.Lookup(".mka")
+	assert.Equal(t, MimeType{Mime: "audio/x-matroska", Category: MimeCategoryAudio}, mime)
+	mime, _ = r.Lookup(".bz2")
+	assert.Equal(t, MimeType{Mime: "application/x-bzip2", Category: MimeCategoryOther}, mime)
+
+	//registered types are kept
+	mime, _ = r.Lookup(".qt")
+	assert.Equal(t, "video/x-custom", mime.Mime)
+	assert.Equal(t, "video/quicktime", r.LookupURL("http://test.com/a.mov", MimeCategoryVideo))
+
+	require.Nil(t, r.LoadMimeTypes(strings.NewReader(testApacheMimeTypes), MimePreferLoaded))
+	mime, _ = r.Lookup(".qt")
+	assert.Equal(t, "video/quicktime", mime.Mime)
+	assert.Equal(t, []string{".qt", ".mov"}, r.ExtensionsForMime("video/quicktime"))
+
+	//category of known type is reused
+	require.Nil(t, r.LoadMimeTypes(strings.NewReader("text/csv tsv\napplication/x-gzip gzip"), MimePreferLoaded))
+	mime, _ = r.Lookup(".tsv")
+	assert.Equal(t, MimeCategoryDocument, mime.Category)
+	mime, _ = r.Lookup(".gzip")
+	assert.Equal(t, MimeCategoryArchive, mime.Category)
+
+	//registry is not changed by invalid file
+	err := r.LoadMimeTypes(strings.NewReader("image/heic heic\nbroken"), MimePreferLoaded)
+	assert.NotNil(t, err)
+	_, ok := r.Lookup(".heic")
+	assert.False(t, ok)
+
+	//default registry is not changed by clone
+	_, ok = DefaultMimeRegistry.Lookup(".mkv")
+	assert.False(t, ok)
+}
+
+func TestLoadMimeTypesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mimetypes")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "mime.types")
+	require.Nil(t, ioutil.WriteFile(path, []byte(testNginxMimeTypes), 0600))
+	broken := filepath.Join(dir, "broken.types")
+	require.Nil(t, ioutil.WriteFile(broken, []byte("\n\nimage"), 0600))
+
+	r := NewMimeRegistry()
+	require.Nil(t, r.LoadMimeTypesFile(path, MimePreferExisting))
+	assert.Equal(t, "image/avif", r.LookupURL("http://test.com/a.avif", ""))
+
+	err = r.LoadMimeTypesFile(broken, MimePreferExisting)
+	assert.Equal(t, &MimeTypesError{File: broken, Line: 3, Msg: `invalid mime type "image"`}, err)
+
+	assert.True(t, os.IsNotExist(r.LoadMimeTypesFile(filepath.Join(dir, "none"), MimePreferExisting)))
+
+	files := SystemMimeTypesFiles
+	defer func() {
+		SystemMimeTypesFiles = files
+	}()
+	SystemMimeTypesFiles = []string{filepath.Join(dir, "none"), path}
+	r = NewMimeRegistry()
+	require.Nil(t, r.LoadSystemMimeTypes())
+	assert.Equal(t, []string{".html", ".htm", ".shtml"}, r.ExtensionsForMime(MimeTextHTML))
+}
+
+func TestWriteMimeTypes(t *testing.T) {
+	r := NewMimeRegistry()
+	require.Nil(t, r.LoadMimeTypes(strings.NewReader(testNginxMimeTypes), MimePreferExisting))
+	require.Nil(t, r.Register(".jpg", MimeImageJpeg, MimeCategoryImage))
+	require.Nil(t, r.Register(".jpeg", MimeImageJpeg, MimeCategoryImage))
+
+	buf := &bytes.Buffer{}
+	require.Nil(t, r.WriteMimeTypes(buf))
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.presentationml.presentation\tpptx\n"+
+		"image/avif\tavif\n"+
+		"image/jpeg\tjpg jpeg\n"+
+		"text/html\thtml htm shtml\n", buf.String())
+
+	//dump can be loaded back
+	loaded := NewMimeRegistry()
+	require.Nil(t, loaded.LoadMimeTypes(bytes.NewReader(buf.Bytes()), MimePreferExisting))
+	assert.Equal(t, r.ExtensionsForMime(MimeImageJpeg), loaded.ExtensionsForMime(MimeImageJpeg))
+}
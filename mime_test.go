@@ -77,6 +77,7 @@ func TestIsValidImageURL(t *testing.T) {
 		"http://test.com/image.jpg":   true,
 		"http://test.t/image.3gpp":    false,
 		"http://test.com/image.gif":   true,
+		"http://test.com/image.webp":  true,
 		"ftp://test.com/image.webm":   false,
 	}
 
@@ -102,3 +103,112 @@ func TestIsValidHTMLURL(t *testing.T) {
 		assert.Equal(t, result, IsValidHTMLURL(sourceURL))
 	}
 }
+
+func TestGetMimeURLParsing(t *testing.T) {
+	//key - source url
+	//val - expexted result
+	testData := map[string]string{
+		"https://cdn.example.com/v.mp4?token=abc":            MimeVideoMp4,
+		"https://cdn.example.com/v.mp4#t=10":                 MimeVideoMp4,
+		"https://cdn.example.com/v.mp4?name=a.webm#part.3gp": MimeVideoMp4,
+		"clip.MP4":                                     MimeVideoMp4,
+		"HTTP://CDN.EXAMPLE.COM/CLIP.WEBM":             MimeVideoWebm,
+		"https://cdn.example.com/download?format=flv":  "",
+		"https://cdn.example.com/download?token=a.mp4": "",
+		"https://cdn.example.com":                      "",
+		"https://cdn.example.com/v.mp4/":               "",
+		"https://cdn.example.com/%zz.mp4":              MimeVideoMp4,
+		"https://cdn.example.com/%zz.mp4?a=b":          MimeVideoMp4,
+	}
+
+	for sourceURL, result := range testData {
+		assert.Equal(t, result, GetVideoMime(sourceURL), sourceURL)
+	}
+
+	assert.True(t, IsValidVideoURL("https://cdn.example.com/v.mp4?token=abc"))
+	assert.True(t, IsValidImageURL("http://test.com/photo.JPG"))
+	assert.True(t, IsValidHTMLURL("http://test.com/index.html?lang=en"))
+	assert.False(t, IsValidVideoURL("https://x.com/page.php?format=mp4"))
+}
+
+func TestGetMimeQueryParams(t *testing.T) {
+	r := DefaultMimeRegistry.Clone()
+	r.SetQueryParams(DefaultMimeQueryParams...)
+
+	//key - source url
+	//val - expexted result
+	testData := map[string]string{
+		"https://cdn.example.com/download?format=flv":          MimeVideoXflv,
+		"https://cdn.example.com/download?ext=.MPG":            MimeVideoMpeg,
+		"https://cdn.example.com/download?ext=mp4&format=webm": MimeVideoWebm,
+		"https://cdn.example.com/v.mp4?format=webm":            MimeVideoMp4,
+		"https://cdn.example.com/page.php?format=mp4":          "",
+		"https://cdn.example.com/download?format=":             "",
+	}
+
+	for sourceURL, result := range testData {
+		assert.Equal(t, result, r.LookupURL(sourceURL, MimeCategoryVideo), sourceURL)
+	}
+
+	//url has single extension, so it matches single category
+	assert.Equal(t, MimeTextHTML, r.LookupURL("https://x.com/a.html?format=mp4", MimeCategoryHTML))
+	assert.Equal(t, "", r.LookupURL("https://x.com/a.html?format=mp4", MimeCategoryVideo))
+
+	r.SetQueryParams()
+	assert.Equal(t, "", r.LookupURL("https://cdn.example.com/download?format=flv", MimeCategoryVideo))
+	assert.Equal(t, "", GetVideoMime("https://cdn.example.com/download?format=flv"))
+}
+
+func TestGetMimeFamilies(t *testing.T) {
+	assert.Equal(t, MimeAudioMpeg, GetAudioMime("http://test.com/song.mp3"))
+	assert.Equal(t, MimeAudioOpus, GetAudioMime("http://test.com/voice.OPUS?x=1"))
+	assert.Equal(t, MimeAudioFlac, GetAudioMime("http://test.com/track.flac"))
+	assert.Equal(t, "", GetAudioMime("http://test.com/video.mp4"))
+
+	assert.Equal(t, MimeApplicationPdf, GetDocumentMime("http://test.com/doc.pdf"))
+	assert.Equal(t, MimeApplicationDocx, GetDocumentMime("http://test.com/doc.docx"))
+	assert.Equal(t, MimeApplicationXlsx, GetDocumentMime("http://test.com/report.xlsx"))
+	assert.Equal(t, MimeTextCSV, GetDocumentMime("http://test.com/export.CSV?page=2"))
+	assert.Equal(t, "", GetDocumentMime("http://test.com/archive.zip"))
+
+	assert.Equal(t, MimeApplicationZip, GetArchiveMime("http://test.com/archive.zip"))
+	assert.Equal(t, MimeApplicationGzip, GetArchiveMime("http://test.com/backup.tar.gz"))
+	assert.Equal(t, MimeApplication7z, GetArchiveMime("http://test.com/backup.7z"))
+
+	assert.Equal(t, MimeFontWoff2, GetFontMime("http://test.com/font.woff2"))
+	assert.Equal(t, MimeFontTtf, GetFontMime("http://test.com/font.ttf"))
+
+	//key - source url
+	//val - expexted mime and category
+	testData := map[string][2]string{
+		"http://test.com/image.png": {MimeImagePng, string(MimeCategoryImage)},
+		"http://test.com/video.mp4": {MimeVideoMp4, string(MimeCategoryVideo)},
+		"http://test.com/song.wav":  {MimeAudioWav, string(MimeCategoryAudio)},
+		"http://test.com/data.json": {MimeApplicationJSON, string(MimeCategoryDocument)},
+		"http://test.com/a.tar":     {MimeApplicationTar, string(MimeCategoryArchive)},
+		"http://test.com/font.otf":  {MimeFontOtf, string(MimeCategoryFont)},
+		"http://test.com/index.htm": {MimeTextHTML, string(MimeCategoryHTML)},
+		"http://test.com/file.xyz":  {"", ""},
+	}
+
+	for sourceURL, result := range testData {
+		mime, category := GetMime(sourceURL)
+		assert.Equal(t, result[0], mime, sourceURL)
+		assert.Equal(t, result[1], string(category), sourceURL)
+	}
+}
+
+func TestIsValidFamilyURL(t *testing.T) {
+	assert.True(t, IsValidAudioURL("http://test.com/song.ogg"))
+	assert.False(t, IsValidAudioURL("http://test./song.ogg"))
+	assert.False(t, IsValidAudioURL("http://test.com/doc.pdf"))
+
+	assert.True(t, IsValidDocumentURL("http://test.com/doc.pdf"))
+	assert.False(t, IsValidDocumentURL("ftp://test.com/doc.pdf"))
+
+	assert.True(t, IsValidArchiveURL("http://test.com/a.zip"))
+	assert.False(t, IsValidArchiveURL("http://test.com/a.woff"))
+
+	assert.True(t, IsValidFontURL("http://test.com/a.woff"))
+	assert.False(t, IsValidFontURL("http://te!st.com/a.woff"))
+}
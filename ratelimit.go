@@ -0,0 +1,85 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//rateLimiter is token bucket limiting client request rate
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(requestsPerSecond float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+//wait blocks until request is allowed or ctx is done.
+//Safe to call on nil limiter.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	//token is reserved even when it is not available yet
+	l.tokens--
+	if l.tokens >= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.release()
+		return ctx.Err()
+	}
+}
+
+//release return reserved token, tokens never exceed burst
+func (l *rateLimiter) release() {
+	l.mu.Lock()
+	l.tokens++
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.mu.Unlock()
+}
+
+// WithRateLimit limit client request rate, every attempt of request
+// waits for free slot. Burst is max number of requests sent at once.
+// Zero requestsPerSecond disables limit.
+func (c *APIClient) WithRateLimit(requestsPerSecond float64, burst int) *APIClient {
+	c.rateLimiter = nil
+	if requestsPerSecond > 0 {
+		c.rateLimiter = newRateLimiter(requestsPerSecond, burst)
+	}
+	return c
+}
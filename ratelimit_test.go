@@ -0,0 +1,46 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(1000).WithRateLimit(20, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		require.Nil(t, client.GetJSON(server.URL, &struct{}{}))
+	}
+	//two requests in burst, two more wait 50ms each
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+
+	assert.Nil(t, client.WithRateLimit(0, 0).rateLimiter)
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	limiter := newRateLimiter(1, 1)
+	require.Nil(t, limiter.wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, limiter.wait(ctx))
+
+	//refill may already reach burst before reserved token is returned
+	limiter = newRateLimiter(1, 2)
+	limiter.release()
+	assert.Equal(t, 2.0, limiter.tokens)
+
+	var nilLimiter *rateLimiter
+	assert.Nil(t, nilLimiter.wait(context.Background()))
+}
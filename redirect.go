@@ -0,0 +1,143 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+//DefaultMaxRedirects max number of redirects when policy does not limit it
+const DefaultMaxRedirects = 10
+
+//RedirectPolicy configure how APIClient follows redirects
+type RedirectPolicy struct {
+	//MaxRedirects max number of followed redirects,
+	//DefaultMaxRedirects when zero. Negative value disables redirects,
+	//then redirect response is checked by success status func.
+	MaxRedirects int
+
+	//SameHostOnly reject redirects to host other than host of original url
+	SameHostOnly bool
+
+	//HTTPSOnly reject redirects to not https urls
+	HTTPSOnly bool
+
+	//CrossHostHeaders when not nil, only listed headers are forwarded
+	//by redirect to other host. Authorization and Cookie headers
+	//are never forwarded to other domain.
+	CrossHostHeaders []string
+}
+
+//RedirectError returned when redirect is rejected by RedirectPolicy
+type RedirectError struct {
+	From   string
+	To     string
+	Reason string
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("redirect from %s to %s rejected: %s", e.From, e.To, e.Reason)
+}
+
+//checkRedirect implements http.Client.CheckRedirect
+func (p RedirectPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if p.MaxRedirects < 0 {
+		return http.ErrUseLastResponse
+	}
+
+	prev := via[len(via)-1]
+	reject := func(reason string) error {
+		return &RedirectError{From: prev.URL.String(), To: req.URL.String(), Reason: reason}
+	}
+
+	max := p.MaxRedirects
+	if max == 0 {
+		max = DefaultMaxRedirects
+	}
+	if len(via) > max {
+		return reject(fmt.Sprintf("stopped after %d redirects", max))
+	}
+	if p.HTTPSOnly && req.URL.Scheme != "https" {
+		return reject("not https url")
+	}
+	if p.SameHostOnly && req.URL.Host != via[0].URL.Host {
+		return reject("other host")
+	}
+
+	if p.CrossHostHeaders != nil && req.URL.Host != prev.URL.Host {
+		allowed := make(map[string]bool, len(p.CrossHostHeaders))
+		for _, name := range p.CrossHostHeaders {
+			allowed[http.CanonicalHeaderKey(name)] = true
+		}
+		for name := range req.Header {
+			if !allowed[http.CanonicalHeaderKey(name)] {
+				req.Header.Del(name)
+			}
+		}
+	}
+	return nil
+}
+
+// WithRedirectPolicy setup redirect following rules
+func (c *APIClient) WithRedirectPolicy(policy RedirectPolicy) *APIClient {
+	c.client.CheckRedirect = policy.checkRedirect
+	return c
+}
+
+//RedirectChain receives urls requested by one APIClient call
+type RedirectChain struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+//URLs return requested urls, first is original url and last is final url
+func (r *RedirectChain) URLs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.urls...)
+}
+
+//FinalURL return url of received response
+//or empty string when response was not received
+func (r *RedirectChain) FinalURL() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.urls) == 0 {
+		return ""
+	}
+	return r.urls[len(r.urls)-1]
+}
+
+type redirectChainKey struct{}
+
+//ContextWithRedirectChain return copy of ctx which makes APIClient
+//record redirect chain of call into chain
+func ContextWithRedirectChain(ctx context.Context, chain *RedirectChain) context.Context {
+	return context.WithValue(ctx, redirectChainKey{}, chain)
+}
+
+//recordRedirectChain stores chain of response requests
+//into RedirectChain of request context
+func recordRedirectChain(req *http.Request, res *http.Response) {
+	chain, ok := req.Context().Value(redirectChainKey{}).(*RedirectChain)
+	if !ok {
+		return
+	}
+
+	var urls []string
+	for r := res.Request; r != nil; {
+		urls = append(urls, r.URL.String())
+		if r.Response == nil {
+			break
+		}
+		r = r.Response.Request
+	}
+	for i, j := 0, len(urls)-1; i < j; i, j = i+1, j-1 {
+		urls[i], urls[j] = urls[j], urls[i]
+	}
+
+	chain.mu.Lock()
+	chain.urls = urls
+	chain.mu.Unlock()
+}
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//newRedirectServer redirects /redirect/N to /redirect/N-1 and /redirect/0 to target
+func newRedirectServer(target string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/redirect/") {
+			w.Write([]byte(`{"id":1,"title":"` + r.Header.Get("X-Forwarded-Test") + `"}`))
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/redirect/"))
+		location := "/redirect/" + strconv.Itoa(n-1)
+		if n == 0 {
+			location = target
+		}
+		http.Redirect(w, r, location, http.StatusFound)
+	}))
+}
+
+func TestAPIClientRedirectChain(t *testing.T) {
+	server := newRedirectServer("/final")
+	defer server.Close()
+
+	chain := &RedirectChain{}
+	ctx := ContextWithRedirectChain(context.Background(), chain)
+	post := &TestPost{}
+	require.Nil(t, NewAPIClient(1000).GetJSONContext(ctx, server.URL+"/redirect/1", post))
+
+	assert.Equal(t, []string{
+		server.URL + "/redirect/1",
+		server.URL + "/redirect/0",
+		server.URL + "/final",
+	}, chain.URLs())
+	assert.Equal(t, server.URL+"/final", chain.FinalURL())
+}
+
+func TestAPIClientRedirectPolicyMaxRedirects(t *testing.T) {
+	server := newRedirectServer("/final")
+	defer server.Close()
+
+	client := NewAPIClient(1000).WithRedirectPolicy(RedirectPolicy{MaxRedirects: 2})
+	post := &TestPost{}
+	require.Nil(t, client.GetJSON(server.URL+"/redirect/1", post))
+
+	err := client.GetJSON(server.URL+"/redirect/2", post)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "stopped after 2 redirects")
+
+	client.WithRedirectPolicy(RedirectPolicy{MaxRedirects: -1})
+	err = client.GetJSON(server.URL+"/redirect/0", post)
+	statusErr, ok := err.(*StatusError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusFound, statusErr.StatusCode)
+}
+
+func TestAPIClientRedirectPolicyHosts(t *testing.T) {
+	target := newRedirectServer("")
+	defer target.Close()
+	//same server on other host name
+	otherHost := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+	server := newRedirectServer(otherHost + "/final")
+	defer server.Close()
+
+	client := NewAPIClient(1000).WithHeaders(map[string]string{
+		"Content-Type":     "application/json",
+		"X-Forwarded-Test": "forwarded",
+	})
+	post := &TestPost{}
+	require.Nil(t, client.GetJSON(server.URL+"/redirect/0", post))
+	assert.Equal(t, "forwarded", post.Title)
+
+	client.WithRedirectPolicy(RedirectPolicy{CrossHostHeaders: []string{"content-type"}})
+	post = &TestPost{}
+	require.Nil(t, client.GetJSON(server.URL+"/redirect/0", post))
+	assert.Empty(t, post.Title)
+
+	client.WithRedirectPolicy(RedirectPolicy{SameHostOnly: true})
+	err := client.GetJSON(server.URL+"/redirect/0", post)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "other host")
+
+	client.WithRedirectPolicy(RedirectPolicy{HTTPSOnly: true})
+	err = client.GetJSON(server.URL+"/redirect/0", post)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not https url")
+}
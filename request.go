@@ -0,0 +1,122 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+//RequestBuilder describes single APIClient call with its own headers,
+//query parameters and timeout. Builder is immutable: every method
+//return new builder, so one builder can be shared between goroutines
+//and used as base for several calls.
+type RequestBuilder struct {
+	client  *APIClient
+	header  http.Header
+	query   url.Values
+	timeout time.Duration
+}
+
+//Request return builder of request with client default settings
+func (c *APIClient) Request() *RequestBuilder {
+	return &RequestBuilder{
+		client: c,
+		header: make(http.Header),
+		query:  make(url.Values),
+	}
+}
+
+func (b *RequestBuilder) clone() *RequestBuilder {
+	clone := *b
+	clone.header = make(http.Header, len(b.header))
+	for name, values := range b.header {
+		clone.header[name] = append([]string(nil), values...)
+	}
+	clone.query = make(url.Values, len(b.query))
+	for name, values := range b.query {
+		clone.query[name] = append([]string(nil), values...)
+	}
+	return &clone
+}
+
+//Header return builder with header set to value.
+//It overrides client default header with same name.
+func (b *RequestBuilder) Header(name, value string) *RequestBuilder {
+	clone := b.clone()
+	clone.header.Set(name, value)
+	return clone
+}
+
+//Query return builder with query parameter added to request url
+func (b *RequestBuilder) Query(name, value string) *RequestBuilder {
+	clone := b.clone()
+	clone.query.Add(name, value)
+	return clone
+}
+
+//Timeout return builder with call timeout.
+//Client timeout is still applied, so shorter of them wins.
+func (b *RequestBuilder) Timeout(timeout time.Duration) *RequestBuilder {
+	clone := b.clone()
+	clone.timeout = timeout
+	return clone
+}
+
+//GetJSON send get request and stores the result
+//in the value pointed to by resp
+func (b *RequestBuilder) GetJSON(ctx context.Context, url string, resp interface{}) error {
+	return b.Do(ctx, http.MethodGet, url, nil, resp)
+}
+
+//PostJSON send post request with JSON encoded reqBody and stores
+//the result in the value pointed to by resp
+func (b *RequestBuilder) PostJSON(ctx context.Context, url string, reqBody, resp interface{}) error {
+	return b.Do(ctx, http.MethodPost, url, reqBody, resp)
+}
+
+//Do send request with method and JSON encoded reqBody to url and stores
+//the result in the value pointed to by resp. GET and HEAD requests
+//and requests with nil reqBody have no body, response body
+//is ignored when resp is nil.
+func (b *RequestBuilder) Do(ctx context.Context, method, rawurl string, reqBody, resp interface{}) error {
+	rawurl = b.client.resolveURL(rawurl)
+	if b.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.timeout)
+		defer cancel()
+	}
+
+	if len(b.query) > 0 {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return err
+		}
+		query := u.Query()
+		for name, values := range b.query {
+			for _, value := range values {
+				query.Add(name, value)
+			}
+		}
+		u.RawQuery = query.Encode()
+		rawurl = u.String()
+	}
+
+	var req *http.Request
+	var err error
+	if reqBody == nil {
+		//nil reqBody means request without body, not JSON null
+		req, err = http.NewRequest(method, rawurl, nil)
+	} else {
+		req, err = newJSONRequest(ctx, method, rawurl, reqBody)
+	}
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range b.header {
+		req.Header[name] = append([]string(nil), values...)
+	}
+
+	return b.client.doJSON(req, resp)
+}
@@ -0,0 +1,145 @@
+package util
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEcho struct {
+	Method      string `json:"method"`
+	Query       string `json:"query"`
+	ContentType string `json:"contentType"`
+	Tenant      string `json:"tenant"`
+}
+
+func newEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.Write([]byte(`{"method":"` + r.Method +
+			`","query":"` + r.URL.RawQuery +
+			`","contentType":"` + r.Header.Get("Content-Type") +
+			`","tenant":"` + r.Header.Get("X-Tenant") + `"}`))
+	}))
+}
+
+func TestRequestBuilder(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	client := NewAPIClient(1000).WithHeaders(map[string]string{
+		"Content-Type": "application/json",
+		"X-Tenant":     "default",
+	})
+	ctx := context.Background()
+
+	base := client.Request().Query("page", "1")
+	withTenant := base.Header("X-Tenant", "acme").Query("sort", "name")
+
+	echo := &testEcho{}
+	require.Nil(t, withTenant.GetJSON(ctx, server.URL+"/items?limit=10", echo))
+	assert.Equal(t, "limit=10&page=1&sort=name", echo.Query)
+	assert.Equal(t, "acme", echo.Tenant)
+	assert.Equal(t, "application/json", echo.ContentType)
+
+	//base builder is not changed by derived one
+	echo = &testEcho{}
+	require.Nil(t, base.PostJSON(ctx, server.URL+"/items", &TestPost{}, echo))
+	assert.Equal(t, http.MethodPost, echo.Method)
+	assert.Equal(t, "page=1", echo.Query)
+	assert.Equal(t, "default", echo.Tenant)
+
+	//client defaults are not changed by builder
+	echo = &testEcho{}
+	require.Nil(t, client.GetJSON(server.URL+"/items", echo))
+	assert.Equal(t, "", echo.Query)
+	assert.Equal(t, "default", echo.Tenant)
+}
+
+func TestRequestBuilderTimeout(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	client := NewAPIClient(1000)
+	echo := &testEcho{}
+	require.NotNil(t, client.Request().Timeout(20*time.Millisecond).
+		GetJSON(context.Background(), server.URL+"/slow", echo))
+	require.Nil(t, client.Request().Timeout(time.Second).
+		Do(context.Background(), http.MethodPut, server.URL+"/slow", &TestPost{}, echo))
+	assert.Equal(t, http.MethodPut, echo.Method)
+}
+
+func TestRequestBuilderConcurrentUse(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	base := NewAPIClient(1000).Request()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(tenant string) {
+			defer wg.Done()
+			echo := &testEcho{}
+			err := base.Header("X-Tenant", tenant).GetJSON(context.Background(), server.URL, echo)
+			assert.Nil(t, err)
+			assert.Equal(t, tenant, echo.Tenant)
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+}
+
+func TestRequestBuilderNilBody(t *testing.T) {
+	var bodies []string
+	mu := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, r.Method+":"+string(body))
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(1000)
+	ctx := context.Background()
+	require.Nil(t, client.Request().Do(ctx, http.MethodPut, server.URL, nil, nil))
+	require.Nil(t, client.Request().Do(ctx, http.MethodDelete, server.URL, nil, nil))
+	require.Nil(t, client.Request().Do(ctx, http.MethodPut, server.URL, &TestPost{ID: 1}, nil))
+	//PostJSON of client keeps encoding nil as JSON null
+	require.Nil(t, client.PostJSON(server.URL, nil, nil))
+	assert.Equal(t, []string{
+		"PUT:",
+		"DELETE:",
+		`PUT:{"userId":0,"id":1,"title":"","body":""}` + "\n",
+		"POST:null\n",
+	}, bodies)
+}
+
+func TestWithHeadersConcurrentUse(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	client := NewAPIClient(1000)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, client.GetJSON(server.URL, &testEcho{}))
+		}()
+		go func(tenant string) {
+			defer wg.Done()
+			client.WithHeaders(map[string]string{"X-Tenant": tenant})
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+}
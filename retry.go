@@ -0,0 +1,167 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+//errorBodyLimit max number of error response body bytes
+//captured for call with contextWithErrorBody
+const errorBodyLimit = 64 << 10
+
+//StatusError returned by APIClient when response status code
+//is not success
+type StatusError struct {
+	StatusCode int
+	URL        string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("response status code %d, url %s", e.StatusCode, e.URL)
+}
+
+type errorBodyKey struct{}
+
+//contextWithErrorBody return copy of ctx which makes APIClient
+//store beginning of not success response body of call into body
+func contextWithErrorBody(ctx context.Context, body *[]byte) context.Context {
+	return context.WithValue(ctx, errorBodyKey{}, body)
+}
+
+//captureErrorBody read beginning of not success response body
+//when it is requested by request context
+func (c *APIClient) captureErrorBody(req *http.Request, res *http.Response) {
+	body, ok := req.Context().Value(errorBodyKey{}).(*[]byte)
+	if !ok {
+		return
+	}
+	limit := int64(errorBodyLimit)
+	if c.maxResponseSize > 0 && c.maxResponseSize < limit {
+		limit = c.maxResponseSize
+	}
+	*body, _ = ioutil.ReadAll(io.LimitReader(res.Body, limit))
+}
+
+//RetryPolicy describes how APIClient repeats failed requests.
+//Transient network errors (timeouts, refused, reset or closed
+//connections, unexpected EOF) and statuses accepted by RetryStatus
+//are retried. Redirect policy, TLS, unsupported scheme and decoding
+//errors are never retried.
+type RetryPolicy struct {
+	//MaxAttempts total number of attempts including first one.
+	//Values less than 2 disable retries.
+	MaxAttempts int
+
+	//Backoff delay before second attempt, doubled for every next attempt
+	Backoff time.Duration
+
+	//MaxBackoff limits delay between attempts, unlimited when zero
+	MaxBackoff time.Duration
+
+	//RetryStatus detects retryable status codes.
+	//By default 429, 502, 503 and 504 are retried.
+	RetryStatus func(statusCode int) bool
+}
+
+func defaultRetryStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+//retryable check that request can be repeated after err
+func (p RetryPolicy) retryable(req *http.Request, err error, attempt int) bool {
+	if attempt >= p.MaxAttempts || req.Context().Err() != nil {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodPost, http.MethodPatch:
+		if len(req.Header.Get(HeaderIdempotencyKey)) == 0 {
+			return false
+		}
+	}
+
+	if statusErr, ok := err.(*StatusError); ok {
+		if p.RetryStatus != nil {
+			return p.RetryStatus(statusErr.StatusCode)
+		}
+		return defaultRetryStatus(statusErr.StatusCode)
+	}
+	return isTransientError(err)
+}
+
+//isTransientError check that transport error may not happen on next attempt,
+//errors which are not returned by transport are never transient
+func isTransientError(err error) bool {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return false
+	}
+	err = urlErr.Err
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	//error of connection closed by server is not exported by net/http
+	return strings.Contains(err.Error(), "server closed idle connection")
+}
+
+//backoff return delay after given attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && delay > 0; i++ {
+		//doubling would overflow time.Duration
+		if delay > math.MaxInt64/2 {
+			delay = math.MaxInt64
+			break
+		}
+		delay *= 2
+		if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+//wait sleeps before next attempt or until ctx is done
+func (p RetryPolicy) wait(ctx context.Context, attempt int) error {
+	delay := p.backoff(attempt)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,148 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFlakyServer(failures int32, status int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"id":1}`))
+	}))
+	return server, &calls
+}
+
+func TestAPIClientRetryGet(t *testing.T) {
+	server, calls := newFlakyServer(2, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	client := NewAPIClient(1000).WithRetry(RetryPolicy{MaxAttempts: 3})
+	post := &TestPost{}
+	require.Nil(t, client.GetJSON(server.URL, post))
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+	assert.Equal(t, 1, post.ID)
+}
+
+func TestAPIClientRetryStopsOnNotRetryableStatus(t *testing.T) {
+	server, calls := newFlakyServer(2, http.StatusNotFound)
+	defer server.Close()
+
+	client := NewAPIClient(1000).WithRetry(RetryPolicy{MaxAttempts: 3})
+	err := client.GetJSON(server.URL, &TestPost{})
+	statusErr, ok := err.(*StatusError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestAPIClientRetryPostRequiresIdempotencyKey(t *testing.T) {
+	server, calls := newFlakyServer(1, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	client := NewAPIClient(1000).WithRetry(RetryPolicy{MaxAttempts: 3})
+	require.NotNil(t, client.PostJSON(server.URL, &TestPost{}, &TestPost{}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 30 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 20*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 30*time.Millisecond, policy.backoff(3))
+	assert.Equal(t, 30*time.Millisecond, policy.backoff(10))
+}
+
+func TestRetryPolicyBackoffOverflow(t *testing.T) {
+	policy := RetryPolicy{Backoff: time.Second}
+	assert.Equal(t, time.Duration(math.MaxInt64), policy.backoff(100))
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.backoff(100))
+}
+
+func TestIsTransientError(t *testing.T) {
+	//key - transport error
+	//val - expected result
+	testData := map[error]bool{
+		ErrInjectedFault:       true,
+		io.EOF:                 true,
+		io.ErrUnexpectedEOF:    true,
+		syscall.ECONNREFUSED:   true,
+		errors.New("redirect"): false,
+		syscall.ECONNABORTED:   false,
+		&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}: true,
+		errors.New("http: server closed idle connection"):                             true,
+	}
+
+	for err, result := range testData {
+		assert.Equal(t, result, isTransientError(&url.Error{Op: "Get", URL: "http://test.com", Err: err}), err.Error())
+		assert.False(t, isTransientError(err), err.Error())
+	}
+}
+
+func TestAPIClientRetryTransportErrors(t *testing.T) {
+	//rejected redirect is not retried
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Redirect(w, r, "/next", http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(1000).WithRetry(RetryPolicy{MaxAttempts: 4, Backoff: time.Millisecond})
+	client.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return errors.New("redirect is not allowed")
+	}
+	require.NotNil(t, client.GetJSON(server.URL, &TestPost{}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	//unsupported scheme is not retried
+	start := time.Now()
+	client = NewAPIClient(1000).WithRetry(RetryPolicy{MaxAttempts: 4, Backoff: time.Second})
+	require.NotNil(t, client.GetJSON("ftp://example.com/", &TestPost{}))
+	assert.True(t, time.Since(start) < time.Second)
+
+	//connection closed without response is retried
+	atomic.StoreInt32(&calls, 0)
+	closing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.Nil(t, err)
+		conn.Close()
+	}))
+	defer closing.Close()
+
+	client = NewAPIClient(1000).WithRetry(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+	require.NotNil(t, client.GetJSON(closing.URL, &TestPost{}))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	//refused connection is retried
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	var dials int32
+	dialer := &net.Dialer{}
+	client = NewAPIClient(1000).WithRetry(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+	client.client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return dialer.DialContext(ctx, network, addr)
+	}
+	require.NotNil(t, client.GetJSON(closed.URL, &TestPost{}))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&dials))
+}
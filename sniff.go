@@ -0,0 +1,135 @@
+package util
+
+import (
+	"bytes"
+	"io"
+)
+
+//MimeSniffLen max number of bytes used by DetectMime
+const MimeSniffLen = 512
+
+//DetectMime return mime type of content by its signature.
+//At most MimeSniffLen bytes are read from r.
+//If format is not supported return empty string.
+func DetectMime(r io.Reader) (string, error) {
+	data := make([]byte, MimeSniffLen)
+	n, err := io.ReadFull(r, data)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return DetectMimeBytes(data[:n]), nil
+}
+
+//DetectMimeBytes return mime type of content by its signature.
+//Only first MimeSniffLen bytes of data are considered.
+//If format is not supported return empty string.
+func DetectMimeBytes(data []byte) string {
+	if len(data) > MimeSniffLen {
+		data = data[:MimeSniffLen]
+	}
+
+	for _, sig := range mimeSignatures {
+		if sig.match(data) {
+			return sig.mime
+		}
+	}
+	if isHTML(data) {
+		return MimeTextHTML
+	}
+	return ""
+}
+
+type mimeSignature struct {
+	mime  string
+	match func(data []byte) bool
+}
+
+var mimeSignatures = []mimeSignature{
+	{MimeImageJpeg, hasPrefix("\xFF\xD8\xFF")},
+	{MimeImagePng, hasPrefix("\x89PNG\r\n\x1A\n")},
+	{MimeImageGif, hasPrefix("GIF87a")},
+	{MimeImageGif, hasPrefix("GIF89a")},
+	{MimeImageWebp, isWebP},
+	{MimeVideoXflv, hasPrefix("FLV\x01")},
+	{MimeVideoWebm, isWebM},
+	{MimeVideo3gpp, is3GPP},
+	{MimeVideoMp4, isMP4},
+	//MPEG program stream and MPEG-1/2 video elementary stream
+	{MimeVideoMpeg, hasPrefix("\x00\x00\x01\xBA")},
+	{MimeVideoMpeg, hasPrefix("\x00\x00\x01\xB3")},
+	{MimeVideoMpeg, isMPEGTS},
+}
+
+func hasPrefix(prefix string) func([]byte) bool {
+	return func(data []byte) bool {
+		return bytes.HasPrefix(data, []byte(prefix))
+	}
+}
+
+func isWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+//isWebM check EBML header, Matroska files other than WebM are not supported
+func isWebM(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("\x1A\x45\xDF\xA3")) &&
+		!bytes.Contains(data, []byte("matroska"))
+}
+
+//ftypBrand return major brand of ISO base media file
+func ftypBrand(data []byte) string {
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return ""
+	}
+	return string(data[8:12])
+}
+
+func is3GPP(data []byte) bool {
+	brand := ftypBrand(data)
+	return len(brand) > 0 && (brand[:3] == "3gp" || brand[:3] == "3g2")
+}
+
+var mp4Brands = map[string]bool{
+	"isom": true, "iso2": true, "iso3": true, "iso4": true, "iso5": true, "iso6": true,
+	"mp41": true, "mp42": true, "mp71": true, "avc1": true, "dash": true, "msnv": true,
+	"M4V ": true, "M4VH": true, "M4VP": true, "F4V ": true, "NDSC": true, "NDSH": true,
+}
+
+func isMP4(data []byte) bool {
+	return mp4Brands[ftypBrand(data)]
+}
+
+//isMPEGTS check sync byte of first transport stream packets
+func isMPEGTS(data []byte) bool {
+	const packetLen = 188
+	if len(data) < packetLen+1 {
+		return false
+	}
+	for i := 0; i < len(data); i += packetLen {
+		if data[i] != 0x47 {
+			return false
+		}
+	}
+	return true
+}
+
+var htmlSignatures = []string{
+	"<!DOCTYPE HTML", "<HTML", "<HEAD", "<SCRIPT", "<IFRAME", "<H1", "<DIV",
+	"<FONT", "<TABLE", "<A", "<STYLE", "<TITLE", "<B", "<BODY", "<BR", "<P", "<!--",
+}
+
+//isHTML check leading html tag, it must be followed by space or '>'
+func isHTML(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	data = bytes.TrimLeft(data, "\t\n\x0C\r ")
+
+	for _, sig := range htmlSignatures {
+		if len(data) <= len(sig) || !bytes.EqualFold(data[:len(sig)], []byte(sig)) {
+			continue
+		}
+		if next := data[len(sig)]; next == ' ' || next == '>' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectMimeBytes(t *testing.T) {
+	ts := bytes.Repeat(append([]byte{0x47}, make([]byte, 187)...), 3)
+
+	//key - content
+	//val - expexted result
+	testData := map[string]string{
+		"":                                      "",
+		"plain text":                            "",
+		"\xFF\xD8\xFF\xE0\x00\x10JFIF":          MimeImageJpeg,
+		"\x89PNG\r\n\x1A\n\x00\x00\x00\x0DIHDR": MimeImagePng,
+		"GIF89a\x01\x00":                        MimeImageGif,
+		"GIF87a\x01\x00":                        MimeImageGif,
+		"RIFF\x24\x00\x00\x00WEBPVP8 ":          MimeImageWebp,
+		"RIFF\x24\x00\x00\x00WAVEfmt ":          "",
+		"FLV\x01\x05\x00\x00\x00\x09":           MimeVideoXflv,
+		"\x1A\x45\xDF\xA3\x9F\x42\x86\x81\x01\x42\x82\x84webm":     MimeVideoWebm,
+		"\x1A\x45\xDF\xA3\xA3\x42\x86\x81\x01\x42\x82\x88matroska": "",
+		"\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00":                 MimeVideoMp4,
+		"\x00\x00\x00\x20ftypisom\x00\x00\x02\x00":                 MimeVideoMp4,
+		"\x00\x00\x00\x1CftypM4V \x00\x00\x00\x01":                 MimeVideoMp4,
+		"\x00\x00\x00\x14ftyp3gp5\x00\x00\x00\x00":                 MimeVideo3gpp,
+		"\x00\x00\x00\x14ftyp3g2a\x00\x00\x00\x00":                 MimeVideo3gpp,
+		"\x00\x00\x00\x18ftypheic\x00\x00\x00\x00":                 "",
+		"\x00\x00\x01\xBA\x44\x00\x04\x00":                         MimeVideoMpeg,
+		"\x00\x00\x01\xB3\x16\x00\xF0\x15":                         MimeVideoMpeg,
+		string(ts):                                                 MimeVideoMpeg,
+		string(ts[:100]):                                           "",
+		"\xEF\xBB\xBF  <!DOCTYPE html><html></html>":               MimeTextHTML,
+		"<HTML><body>":                                             MimeTextHTML,
+		"\n<p class=\"a\">text</p>":                                MimeTextHTML,
+		"<php echo 1;":                                             "",
+		"<html":                                                    "",
+	}
+
+	for content, result := range testData {
+		assert.Equal(t, result, DetectMimeBytes([]byte(content)), "%q", content)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestDetectMime(t *testing.T) {
+	content := append([]byte("\x89PNG\r\n\x1A\n"), make([]byte, 2*MimeSniffLen)...)
+	r := bytes.NewReader(content)
+	mime, err := DetectMime(r)
+	assert.Nil(t, err)
+	assert.Equal(t, MimeImagePng, mime)
+	assert.Equal(t, len(content)-MimeSniffLen, r.Len())
+
+	mime, err = DetectMime(bytes.NewReader([]byte("GIF89a")))
+	assert.Nil(t, err)
+	assert.Equal(t, MimeImageGif, mime)
+
+	_, err = DetectMime(errReader{})
+	assert.NotNil(t, err)
+}
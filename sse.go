@@ -0,0 +1,299 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//DefaultSSERetry delay before reconnect when server did not send retry field
+const DefaultSSERetry = 3 * time.Second
+
+//maxSSELineSize max size of single line in event stream
+const maxSSELineSize = 1 << 20
+
+//SSEContentTypeError returned when server answers
+//with content type other than text/event-stream
+type SSEContentTypeError struct {
+	ContentType string
+	URL         string
+}
+
+func (e *SSEContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected content type %q of event stream, url %s", e.ContentType, e.URL)
+}
+
+//SSEEvent is event received from text/event-stream
+type SSEEvent struct {
+	//ID last event id, it is sent in Last-Event-ID header on reconnect
+	ID string
+
+	//Event event type, "message" when not set by server
+	Event string
+
+	//Data event data, lines are joined by "\n"
+	Data string
+}
+
+//SSESubscription delivers events of server-sent events stream
+type SSESubscription struct {
+	events chan SSEEvent
+
+	mu  sync.Mutex
+	err error
+}
+
+//Events return channel with received events.
+//Channel is closed when subscription context is done,
+//server rejected reconnection or stream has too long line.
+func (s *SSESubscription) Events() <-chan SSEEvent {
+	return s.events
+}
+
+//Err return reason of closing Events channel or, while channel
+//is open, last read error of stream which is cleared after
+//successful reconnection. It is nil when context was cancelled.
+func (s *SSESubscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *SSESubscription) setErr(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+//SubscribeSSE connect to server-sent events stream at url.
+//Client default headers and transport are reused, but client timeout
+//is not applied to the stream. Broken connection is restored
+//with Last-Event-ID header after server defined retry delay.
+//Error is returned when first connection failed, response with
+//content type other than text/event-stream fails with *SSEContentTypeError.
+func (c *APIClient) SubscribeSSE(ctx context.Context, url string) (*SSESubscription, error) {
+	stream := &sseStream{
+		client: c,
+		http:   c.streamingClient(),
+		url:    c.resolveURL(url),
+		retry:  DefaultSSERetry,
+	}
+
+	body, err := stream.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &SSESubscription{events: make(chan SSEEvent)}
+	go stream.run(ctx, body, sub)
+	return sub, nil
+}
+
+//streamingClient return copy of http client without overall timeout
+func (c *APIClient) streamingClient() *http.Client {
+	client := *c.client
+	client.Timeout = 0
+	return &client
+}
+
+//sseStream holds reconnection state of subscription
+type sseStream struct {
+	client      *APIClient
+	http        *http.Client
+	url         string
+	lastEventID string
+	retry       time.Duration
+}
+
+func (s *sseStream) connect(ctx context.Context) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	for name, val := range s.client.defaultHeaders() {
+		req.Header.Set(name, val)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if len(s.lastEventID) > 0 {
+		req.Header.Set("Last-Event-ID", s.lastEventID)
+	}
+
+	res, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if !s.client.isSuccessStatus(res.StatusCode) || res.StatusCode == http.StatusNoContent {
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		return nil, &StatusError{StatusCode: res.StatusCode, URL: s.url}
+	}
+	contentType := res.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "text/event-stream" {
+		res.Body.Close()
+		return nil, &SSEContentTypeError{ContentType: contentType, URL: s.url}
+	}
+	return res.Body, nil
+}
+
+func (s *sseStream) run(ctx context.Context, body io.ReadCloser, sub *SSESubscription) {
+	defer func() {
+		if ctx.Err() != nil {
+			sub.setErr(nil)
+		}
+		close(sub.events)
+	}()
+
+	for {
+		err := s.read(ctx, body, sub.events)
+		body.Close()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			sub.setErr(err)
+			//server would send the same line again after reconnect
+			if err == bufio.ErrTooLong {
+				return
+			}
+		}
+
+		timer := time.NewTimer(s.retry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		body, err = s.connect(ctx)
+		for err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			//server rejected stream, reconnection makes no sense
+			switch err.(type) {
+			case *StatusError, *SSEContentTypeError:
+				sub.setErr(err)
+				return
+			}
+
+			timer := time.NewTimer(s.retry)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			body, err = s.connect(ctx)
+		}
+		sub.setErr(nil)
+	}
+}
+
+//read parse stream until it ends and deliver events.
+//Return read error, nil when stream ended normally.
+func (s *sseStream) read(ctx context.Context, body io.Reader, events chan<- SSEEvent) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 4096), maxSSELineSize)
+	scanner.Split(scanSSELines)
+
+	var eventType string
+	var data bytes.Buffer
+	hasData := false
+	first := true
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		//stream may start with UTF-8 byte order mark
+		if first {
+			line = strings.TrimPrefix(line, "\uFEFF")
+			first = false
+		}
+
+		if len(line) == 0 {
+			if hasData {
+				event := SSEEvent{
+					ID:    s.lastEventID,
+					Event: eventType,
+					Data:  strings.TrimSuffix(data.String(), "\n"),
+				}
+				if len(event.Event) == 0 {
+					event.Event = "message"
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+			eventType = ""
+			data.Reset()
+			hasData = false
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+//scanSSELines split stream by CRLF, LF or CR line endings
+func scanSSELines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		//CR at the end of buffer may be followed by LF
+		if i+1 == len(data) && !atEOF {
+			return 0, nil, nil
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+	//incomplete line at the end of stream is dropped
+	if atEOF {
+		return len(data), nil, nil
+	}
+	return 0, nil, nil
+}
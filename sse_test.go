@@ -0,0 +1,158 @@
+package util
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanSSELines(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("a\r\nb\nc\rd\r\n\r\ntail"))
+	scanner.Split(scanSSELines)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d", ""}, lines)
+}
+
+func TestAPIClientSubscribeSSE(t *testing.T) {
+	var mu sync.Mutex
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		connection := len(lastEventIDs)
+		mu.Unlock()
+
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if connection == 1 {
+			w.Write([]byte(": comment\nretry: 10\n\n" +
+				"id: 1\nevent: update\ndata: first\ndata:  line\n\n" +
+				"data: no id change\n\n"))
+			return
+		}
+		w.Write([]byte("id: 2\ndata: after reconnect\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := NewAPIClient(100).SubscribeSSE(ctx, server.URL)
+	require.Nil(t, err)
+
+	var events []SSEEvent
+	for event := range sub.Events() {
+		events = append(events, event)
+		if len(events) == 3 {
+			//stream must survive client timeout
+			time.Sleep(150 * time.Millisecond)
+			cancel()
+		}
+	}
+
+	require.Len(t, events, 3)
+	assert.Equal(t, SSEEvent{ID: "1", Event: "update", Data: "first\n line"}, events[0])
+	assert.Equal(t, SSEEvent{ID: "1", Event: "message", Data: "no id change"}, events[1])
+	assert.Equal(t, SSEEvent{ID: "2", Event: "message", Data: "after reconnect"}, events[2])
+	assert.Nil(t, sub.Err())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"", "1"}, lastEventIDs)
+}
+
+func TestAPIClientSubscribeSSEStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := NewAPIClient(1000).SubscribeSSE(context.Background(), server.URL)
+	statusErr, ok := err.(*StatusError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, statusErr.StatusCode)
+}
+
+func TestAPIClientSubscribeSSEContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"error":"not a stream"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		w.Write([]byte("\uFEFFdata: first\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	_, err := NewAPIClient(1000).SubscribeSSE(context.Background(), server.URL+"/json")
+	contentTypeErr, ok := err.(*SSEContentTypeError)
+	require.True(t, ok, "%v", err)
+	assert.Equal(t, "application/json", contentTypeErr.ContentType)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := NewAPIClient(1000).SubscribeSSE(ctx, server.URL)
+	require.Nil(t, err)
+	select {
+	case event := <-sub.Events():
+		assert.Equal(t, SSEEvent{Event: "message", Data: "first"}, event)
+	case <-time.After(time.Second):
+		t.Fatal("event with byte order mark is not received")
+	}
+}
+
+func TestAPIClientSubscribeSSETooLongLine(t *testing.T) {
+	var mu sync.Mutex
+	connections := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connections++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("retry: 10\ndata: short\n\n"))
+		w.Write([]byte("data: " + strings.Repeat("x", maxSSELineSize) + "\n\n"))
+	}))
+	defer server.Close()
+
+	sub, err := NewAPIClient(1000).SubscribeSSE(context.Background(), server.URL)
+	require.Nil(t, err)
+
+	var events []SSEEvent
+	timeout := time.After(2 * time.Second)
+loop:
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				break loop
+			}
+			events = append(events, event)
+		case <-timeout:
+			t.Fatal("subscription is not closed")
+		}
+	}
+
+	assert.Equal(t, []SSEEvent{{Event: "message", Data: "short"}}, events)
+	assert.Equal(t, bufio.ErrTooLong, sub.Err())
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 1, connections)
+	mu.Unlock()
+}
@@ -0,0 +1,223 @@
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//HeaderTraceparent W3C trace context traceparent header
+	HeaderTraceparent = "Traceparent"
+
+	//HeaderTracestate W3C trace context tracestate header
+	HeaderTracestate = "Tracestate"
+)
+
+//TraceID identifies whole distributed trace
+type TraceID [16]byte
+
+//SpanID identifies single span inside trace
+type SpanID [8]byte
+
+//String return lowercase hex representation of trace id
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+//IsValid check that trace id is not all zeros
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+//String return lowercase hex representation of span id
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+//IsValid check that span id is not all zeros
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+//SpanContext is propagated part of span, it corresponds to
+//W3C traceparent and tracestate headers
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+//IsValid check that span context has trace and span ids
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+//Traceparent return traceparent header value for span context
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+//ErrInvalidTraceparent returned when traceparent header can not be parsed
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+//ParseTraceparent parse W3C traceparent header value
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 ||
+		len(parts[0]) != 2 || parts[0] == "ff" ||
+		(parts[0] == "00" && len(parts) != 4) ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, ErrInvalidTraceparent
+	}
+	if strings.ToLower(value) != value {
+		return sc, ErrInvalidTraceparent
+	}
+
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	if !sc.IsValid() {
+		return sc, ErrInvalidTraceparent
+	}
+	sc.Sampled = flags[0]&1 == 1
+
+	return sc, nil
+}
+
+//SpanContextFromRequest extract span context from request
+//traceparent and tracestate headers
+func SpanContextFromRequest(req *http.Request) (SpanContext, error) {
+	sc, err := ParseTraceparent(req.Header.Get(HeaderTraceparent))
+	if err != nil {
+		return sc, err
+	}
+	sc.TraceState = req.Header.Get(HeaderTracestate)
+	return sc, nil
+}
+
+type spanContextKey struct{}
+
+//ContextWithSpanContext return copy of ctx carrying span context.
+//APIClient uses it as parent of client spans.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+//SpanContextFromContext return span context stored in ctx
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+//Span describes finished client request
+type Span struct {
+	Name         string
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]string
+
+	//Err is request error or nil when response was received
+	Err error
+}
+
+//SpanExporter receives finished spans.
+//ExportSpan is called synchronously at the end of every request,
+//so implementation must be fast and safe for concurrent use.
+type SpanExporter interface {
+	ExportSpan(span *Span)
+}
+
+//InMemoryExporter stores exported spans in memory, useful in tests
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+//ExportSpan implements SpanExporter
+func (e *InMemoryExporter) ExportSpan(span *Span) {
+	e.mu.Lock()
+	e.spans = append(e.spans, span)
+	e.mu.Unlock()
+}
+
+//Spans return copy of exported spans slice
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	spans := make([]*Span, len(e.spans))
+	copy(spans, e.spans)
+	return spans
+}
+
+//Reset remove all exported spans
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
+
+//startClientSpan create client span for request, inject trace headers
+//and return func which finish and export span.
+//When exporter is nil tracing is disabled.
+func startClientSpan(exporter SpanExporter, req *http.Request) func(res *http.Response, err error) {
+	if exporter == nil {
+		return func(*http.Response, error) {}
+	}
+
+	span := &Span{
+		Name:  "HTTP " + req.Method,
+		Start: time.Now(),
+		Attributes: map[string]string{
+			"http.method": req.Method,
+			"http.url":    req.URL.String(),
+			"net.peer":    req.URL.Host,
+		},
+	}
+
+	parent, ok := SpanContextFromContext(req.Context())
+	if ok {
+		span.SpanContext = parent
+		span.ParentSpanID = parent.SpanID
+	} else {
+		rand.Read(span.SpanContext.TraceID[:])
+		span.SpanContext.Sampled = true
+	}
+	rand.Read(span.SpanContext.SpanID[:])
+
+	req.Header.Set(HeaderTraceparent, span.SpanContext.Traceparent())
+	if len(span.SpanContext.TraceState) > 0 {
+		req.Header.Set(HeaderTracestate, span.SpanContext.TraceState)
+	}
+
+	return func(res *http.Response, err error) {
+		span.End = time.Now()
+		span.Err = err
+		if res != nil {
+			span.Attributes["http.status_code"] = strconv.Itoa(res.StatusCode)
+		}
+		exporter.ExportSpan(span)
+	}
+}
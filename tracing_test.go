@@ -0,0 +1,86 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.Nil(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	//key - header value
+	//val - expected error
+	testData := map[string]error{
+		"": ErrInvalidTraceparent,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7":    ErrInvalidTraceparent,
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01": ErrInvalidTraceparent,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01": ErrInvalidTraceparent,
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01": ErrInvalidTraceparent,
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01": ErrInvalidTraceparent,
+		"00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01": ErrInvalidTraceparent,
+	}
+	for value, result := range testData {
+		_, err := ParseTraceparent(value)
+		assert.Equal(t, result, err, value)
+	}
+}
+
+func TestAPIClientTracingPropagatesParent(t *testing.T) {
+	var received SpanContext
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = SpanContextFromRequest(r)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	exporter := &InMemoryExporter{}
+	client := NewAPIClient(1000).WithTracing(exporter)
+
+	parent, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.Nil(t, err)
+	parent.TraceState = "vendor=value"
+	ctx := ContextWithSpanContext(context.Background(), parent)
+
+	post := &TestPost{}
+	require.Nil(t, client.GetJSONContext(ctx, server.URL, post))
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, parent.TraceID, span.SpanContext.TraceID)
+	assert.Equal(t, parent.SpanID, span.ParentSpanID)
+	assert.NotEqual(t, parent.SpanID, span.SpanContext.SpanID)
+	assert.Equal(t, "200", span.Attributes["http.status_code"])
+	assert.Nil(t, span.Err)
+
+	assert.Equal(t, span.SpanContext.TraceID, received.TraceID)
+	assert.Equal(t, span.SpanContext.SpanID, received.SpanID)
+	assert.Equal(t, "vendor=value", received.TraceState)
+
+	exporter.Reset()
+	assert.Empty(t, exporter.Spans())
+}
+
+func TestAPIClientTracingNewTrace(t *testing.T) {
+	exporter := &InMemoryExporter{}
+	client := NewAPIClient(150).WithTracing(exporter)
+
+	post := &TestPost{}
+	require.NotNil(t, client.GetJSON("http://127.0.0.1:1/", post))
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 1)
+	assert.True(t, spans[0].SpanContext.IsValid())
+	assert.False(t, spans[0].ParentSpanID.IsValid())
+	assert.NotNil(t, spans[0].Err)
+}
@@ -0,0 +1,268 @@
+//Package utiltest provides test doubles for util package types
+package utiltest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/crazyslon/util"
+)
+
+//TestingT is subset of *testing.T used by assertions
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+//Call is request recorded by FakeClient
+type Call struct {
+	Method string
+	URL    string
+
+	//Body request body of POST call, nil for GET
+	Body interface{}
+}
+
+//HandlerFunc computes fake response for call without configured response
+type HandlerFunc func(call Call) (resp interface{}, err error)
+
+type fakeResponse struct {
+	resp interface{}
+	err  error
+}
+
+//FakeClient is configurable util.JSONClient implementation
+//which records all calls. Responses are copied into destination
+//through JSON encoding, like real client decodes response body.
+//Call without configured response and handler fails with 404 StatusError.
+type FakeClient struct {
+	mu        sync.Mutex
+	responses map[string]fakeResponse
+	handler   HandlerFunc
+	calls     []Call
+}
+
+var _ util.JSONClient = (*FakeClient)(nil)
+
+//NewFakeClient create fake client without responses
+func NewFakeClient() *FakeClient {
+	return &FakeClient{responses: make(map[string]fakeResponse)}
+}
+
+func callKey(method, url string) string {
+	return method + " " + url
+}
+
+//RespondGet setup response of GET request to url.
+//When err is not nil it is returned instead of response.
+func (f *FakeClient) RespondGet(url string, resp interface{}, err error) *FakeClient {
+	return f.respond(http.MethodGet, url, resp, err)
+}
+
+//RespondPost setup response of POST request to url.
+//When err is not nil it is returned instead of response.
+func (f *FakeClient) RespondPost(url string, resp interface{}, err error) *FakeClient {
+	return f.respond(http.MethodPost, url, resp, err)
+}
+
+func (f *FakeClient) respond(method, url string, resp interface{}, err error) *FakeClient {
+	f.mu.Lock()
+	f.responses[callKey(method, url)] = fakeResponse{resp: resp, err: err}
+	f.mu.Unlock()
+	return f
+}
+
+//WithHandler setup handler for calls without configured response
+func (f *FakeClient) WithHandler(handler HandlerFunc) *FakeClient {
+	f.mu.Lock()
+	f.handler = handler
+	f.mu.Unlock()
+	return f
+}
+
+//GetJSON implements util.JSONClient
+func (f *FakeClient) GetJSON(url string, resp interface{}) error {
+	return f.GetJSONContext(context.Background(), url, resp)
+}
+
+//GetJSONContext implements util.JSONClient
+func (f *FakeClient) GetJSONContext(ctx context.Context, url string, resp interface{}) error {
+	return f.do(ctx, Call{Method: http.MethodGet, URL: url}, resp)
+}
+
+//PostJSON implements util.JSONClient
+func (f *FakeClient) PostJSON(url string, reqBody, resp interface{}) error {
+	return f.PostJSONContext(context.Background(), url, reqBody, resp)
+}
+
+//PostJSONContext implements util.JSONClient
+func (f *FakeClient) PostJSONContext(ctx context.Context, url string, reqBody, resp interface{}) error {
+	return f.do(ctx, Call{Method: http.MethodPost, URL: url, Body: reqBody}, resp)
+}
+
+//Batch implements util.JSONClient, requests are executed sequentially.
+//With FailFast requests after first failed one get context.Canceled.
+func (f *FakeClient) Batch(ctx context.Context, reqs []util.BatchRequest, opts util.BatchOptions) []error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	errs := make([]error, len(reqs))
+	failed := false
+	for i, req := range reqs {
+		if failed {
+			errs[i] = context.Canceled
+			continue
+		}
+		if req.Body != nil {
+			errs[i] = f.PostJSONContext(ctx, req.URL, req.Body, req.Resp)
+		} else {
+			errs[i] = f.GetJSONContext(ctx, req.URL, req.Resp)
+		}
+		failed = errs[i] != nil && opts.FailFast
+	}
+	return errs
+}
+
+//GetJSONBatch implements util.JSONClient
+func (f *FakeClient) GetJSONBatch(ctx context.Context, urls []string,
+	newResp func() interface{}, opts util.BatchOptions) []util.BatchResult {
+	reqs := make([]util.BatchRequest, len(urls))
+	for i, url := range urls {
+		reqs[i] = util.BatchRequest{URL: url, Resp: newResp()}
+	}
+
+	errs := f.Batch(ctx, reqs, opts)
+
+	results := make([]util.BatchResult, len(urls))
+	for i, req := range reqs {
+		results[i] = util.BatchResult{URL: req.URL, Resp: req.Resp, Err: errs[i]}
+	}
+	return results
+}
+
+//LoginSession implements util.JSONClient, it is recorded as POST
+//of credentials to loginURL and gets response configured for it
+func (f *FakeClient) LoginSession(ctx context.Context, loginURL string, credentials, resp interface{}) error {
+	return f.PostJSONContext(ctx, loginURL, credentials, resp)
+}
+
+func (f *FakeClient) do(ctx context.Context, call Call, resp interface{}) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	response, ok := f.responses[callKey(call.Method, call.URL)]
+	handler := f.handler
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !ok {
+		if handler == nil {
+			return &util.StatusError{StatusCode: http.StatusNotFound, URL: call.URL}
+		}
+		response.resp, response.err = handler(call)
+	}
+	if response.err != nil {
+		return response.err
+	}
+	if resp == nil || response.resp == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(response.resp)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, resp)
+}
+
+//Calls return copy of recorded calls
+func (f *FakeClient) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+//Reset remove recorded calls, configured responses are kept
+func (f *FakeClient) Reset() {
+	f.mu.Lock()
+	f.calls = nil
+	f.mu.Unlock()
+}
+
+//CallCount return number of calls with method to url
+func (f *FakeClient) CallCount(method, url string) int {
+	count := 0
+	for _, call := range f.Calls() {
+		if call.Method == method && call.URL == url {
+			count++
+		}
+	}
+	return count
+}
+
+//AssertCalled check that method was called for url at least once
+func (f *FakeClient) AssertCalled(t TestingT, method, url string) bool {
+	if f.CallCount(method, url) == 0 {
+		t.Errorf("expected call %s %s, recorded calls: %v", method, url, f.Calls())
+		return false
+	}
+	return true
+}
+
+//AssertNotCalled check that method was never called for url
+func (f *FakeClient) AssertNotCalled(t TestingT, method, url string) bool {
+	if count := f.CallCount(method, url); count > 0 {
+		t.Errorf("unexpected call %s %s, called %d times", method, url, count)
+		return false
+	}
+	return true
+}
+
+//AssertNumberOfCalls check total number of recorded calls
+func (f *FakeClient) AssertNumberOfCalls(t TestingT, expected int) bool {
+	if calls := f.Calls(); len(calls) != expected {
+		t.Errorf("expected %d calls, recorded %d: %v", expected, len(calls), calls)
+		return false
+	}
+	return true
+}
+
+//AssertPostedBody check that POST request to url was sent with body
+//equal to expected after JSON encoding
+func (f *FakeClient) AssertPostedBody(t TestingT, url string, expected interface{}) bool {
+	expectedJSON, err := normalizeJSON(expected)
+	if err != nil {
+		t.Errorf("can not encode expected body: %v", err)
+		return false
+	}
+
+	for _, call := range f.Calls() {
+		if call.Method != http.MethodPost || call.URL != url {
+			continue
+		}
+		actualJSON, err := normalizeJSON(call.Body)
+		if err == nil && reflect.DeepEqual(expectedJSON, actualJSON) {
+			return true
+		}
+	}
+	t.Errorf("expected POST %s with body %v, recorded calls: %v", url, expected, f.Calls())
+	return false
+}
+
+//normalizeJSON convert value to generic JSON representation
+func normalizeJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
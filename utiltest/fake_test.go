@@ -0,0 +1,117 @@
+package utiltest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/crazyslon/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+//userService is example of code depending on util.JSONClient
+type userService struct {
+	client util.JSONClient
+}
+
+func (s *userService) rename(id int, name string) (*testUser, error) {
+	user := &testUser{}
+	url := fmt.Sprintf("https://api.test/users/%d", id)
+	if err := s.client.GetJSON(url, user); err != nil {
+		return nil, err
+	}
+	user.Name = name
+	return user, s.client.PostJSON(url, user, user)
+}
+
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestFakeClient(t *testing.T) {
+	fake := NewFakeClient().
+		RespondGet("https://api.test/users/1", map[string]interface{}{"id": 1, "name": "old"}, nil).
+		RespondPost("https://api.test/users/1", &testUser{ID: 1, Name: "new"}, nil)
+
+	user, err := (&userService{client: fake}).rename(1, "new")
+	require.Nil(t, err)
+	assert.Equal(t, &testUser{ID: 1, Name: "new"}, user)
+
+	fake.AssertCalled(t, http.MethodGet, "https://api.test/users/1")
+	fake.AssertPostedBody(t, "https://api.test/users/1", map[string]interface{}{"id": 1, "name": "new"})
+	fake.AssertNotCalled(t, http.MethodGet, "https://api.test/users/2")
+	fake.AssertNumberOfCalls(t, 2)
+
+	_, err = (&userService{client: fake}).rename(2, "new")
+	statusErr, ok := err.(*util.StatusError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+
+	failing := errors.New("boom")
+	fake.Reset()
+	fake.RespondGet("https://api.test/users/1", nil, failing)
+	_, err = (&userService{client: fake}).rename(1, "new")
+	assert.Equal(t, failing, err)
+	fake.AssertNumberOfCalls(t, 1)
+}
+
+func TestFakeClientHandler(t *testing.T) {
+	fake := NewFakeClient().WithHandler(func(call Call) (interface{}, error) {
+		return &testUser{ID: len(call.URL)}, nil
+	})
+
+	user := &testUser{}
+	require.Nil(t, fake.GetJSON("https://a", user))
+	assert.Equal(t, 9, user.ID)
+	assert.Equal(t, 1, fake.CallCount(http.MethodGet, "https://a"))
+}
+
+func TestFakeClientBatch(t *testing.T) {
+	fake := NewFakeClient().
+		RespondGet("https://api.test/users/1", &testUser{ID: 1}, nil).
+		RespondPost("https://api.test/login", nil, nil)
+	ctx := context.Background()
+
+	results := fake.GetJSONBatch(ctx, []string{"https://api.test/users/1", "https://api.test/users/2"},
+		func() interface{} { return &testUser{} }, util.BatchOptions{})
+	require.Len(t, results, 2)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, &testUser{ID: 1}, results[0].Resp)
+	assert.NotNil(t, results[1].Err)
+
+	errs := fake.Batch(ctx, []util.BatchRequest{
+		{URL: "https://api.test/users/2"},
+		{URL: "https://api.test/users/1"},
+	}, util.BatchOptions{FailFast: true})
+	assert.NotNil(t, errs[0])
+	assert.Equal(t, context.Canceled, errs[1])
+
+	require.Nil(t, fake.LoginSession(ctx, "https://api.test/login", map[string]string{"login": "user"}, nil))
+	fake.AssertPostedBody(t, "https://api.test/login", map[string]string{"login": "user"})
+	fake.AssertNumberOfCalls(t, 4)
+}
+
+func TestFakeClientAssertionsFail(t *testing.T) {
+	fake := NewFakeClient()
+	rec := &recordingT{}
+
+	assert.False(t, fake.AssertCalled(rec, http.MethodGet, "https://a"))
+	assert.False(t, fake.AssertNumberOfCalls(rec, 1))
+	assert.False(t, fake.AssertPostedBody(rec, "https://a", &testUser{}))
+
+	fake.GetJSON("https://a", nil)
+	assert.False(t, fake.AssertNotCalled(rec, http.MethodGet, "https://a"))
+	assert.Len(t, rec.errors, 4)
+}
@@ -0,0 +1,334 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Validator is implemented by response types which check
+//their own content after decoding
+type Validator interface {
+	Validate() error
+}
+
+//ValidationProblem describes single invalid value of response
+type ValidationProblem struct {
+	//Path location of value, "$" is response root,
+	//e.g. $.items[0].id
+	Path string
+
+	Message string
+}
+
+//ValidationError returned when decoded response is not valid
+type ValidationError struct {
+	URL      string
+	Problems []ValidationProblem
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		problems[i] = p.Path + ": " + p.Message
+	}
+	return fmt.Sprintf("invalid response, url %s: %s", e.URL, strings.Join(problems, "; "))
+}
+
+// WithResponseValidation enable call of Validate method
+// for responses which implement Validator.
+// Validation errors are returned as *ValidationError.
+func (c *APIClient) WithResponseValidation() *APIClient {
+	c.validateResponses = true
+	return c
+}
+
+type responseSchemaKey struct{}
+
+//ContextWithResponseSchema return copy of ctx which makes APIClient
+//check response body of call against schema before decoding
+func ContextWithResponseSchema(ctx context.Context, schema *JSONSchema) context.Context {
+	return context.WithValue(ctx, responseSchemaKey{}, schema)
+}
+
+func responseSchemaFromRequest(req *http.Request) *JSONSchema {
+	schema, _ := req.Context().Value(responseSchemaKey{}).(*JSONSchema)
+	return schema
+}
+
+//validateResponse call Validate of resp when it is enabled
+func (c *APIClient) validateResponse(req *http.Request, resp interface{}) error {
+	v, ok := resp.(Validator)
+	if !c.validateResponses || !ok {
+		return nil
+	}
+
+	err := v.Validate()
+	if err == nil {
+		return nil
+	}
+	if verr, ok := err.(*ValidationError); ok {
+		return &ValidationError{URL: req.URL.String(), Problems: verr.Problems}
+	}
+	return &ValidationError{
+		URL:      req.URL.String(),
+		Problems: []ValidationProblem{{Path: "$", Message: err.Error()}},
+	}
+}
+
+//JSONSchema is compiled JSON Schema document.
+//Supported keywords: type, enum, properties, required,
+//additionalProperties, items, minItems, maxItems,
+//minLength, maxLength, pattern, minimum and maximum.
+//Other keywords are ignored.
+type JSONSchema struct {
+	types                []string
+	enum                 []interface{}
+	properties           map[string]*JSONSchema
+	required             []string
+	additionalProperties *JSONSchema
+	noAdditional         bool
+	items                *JSONSchema
+	minItems, maxItems   *int
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+}
+
+//jsonSchemaDoc is JSON representation of schema
+type jsonSchemaDoc struct {
+	Type                 json.RawMessage            `json:"type"`
+	Enum                 []interface{}              `json:"enum"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	MinItems             *int                       `json:"minItems"`
+	MaxItems             *int                       `json:"maxItems"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+	Pattern              *string                    `json:"pattern"`
+	Minimum              *float64                   `json:"minimum"`
+	Maximum              *float64                   `json:"maximum"`
+}
+
+var jsonSchemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+//ParseJSONSchema compile JSON Schema document
+func ParseJSONSchema(data []byte) (*JSONSchema, error) {
+	return parseJSONSchema(data, "$")
+}
+
+func parseJSONSchema(data []byte, path string) (*JSONSchema, error) {
+	doc := &jsonSchemaDoc{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("schema %s: %v", path, err)
+	}
+
+	schema := &JSONSchema{
+		enum:      doc.Enum,
+		required:  doc.Required,
+		minItems:  doc.MinItems,
+		maxItems:  doc.MaxItems,
+		minLength: doc.MinLength,
+		maxLength: doc.MaxLength,
+		minimum:   doc.Minimum,
+		maximum:   doc.Maximum,
+	}
+
+	if len(doc.Type) > 0 {
+		var one string
+		if err := json.Unmarshal(doc.Type, &one); err == nil {
+			schema.types = []string{one}
+		} else if err := json.Unmarshal(doc.Type, &schema.types); err != nil {
+			return nil, fmt.Errorf("schema %s: type must be string or array of strings", path)
+		}
+		for _, t := range schema.types {
+			if !jsonSchemaTypes[t] {
+				return nil, fmt.Errorf("schema %s: unknown type %q", path, t)
+			}
+		}
+	}
+
+	if doc.Pattern != nil {
+		re, err := regexp.Compile(*doc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %v", path, err)
+		}
+		schema.pattern = re
+	}
+
+	if len(doc.Properties) > 0 {
+		schema.properties = make(map[string]*JSONSchema, len(doc.Properties))
+		for name, raw := range doc.Properties {
+			prop, err := parseJSONSchema(raw, path+"."+name)
+			if err != nil {
+				return nil, err
+			}
+			schema.properties[name] = prop
+		}
+	}
+
+	if len(doc.AdditionalProperties) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(doc.AdditionalProperties, &allowed); err == nil {
+			schema.noAdditional = !allowed
+		} else {
+			additional, err := parseJSONSchema(doc.AdditionalProperties, path+".*")
+			if err != nil {
+				return nil, err
+			}
+			schema.additionalProperties = additional
+		}
+	}
+
+	if len(doc.Items) > 0 {
+		items, err := parseJSONSchema(doc.Items, path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		schema.items = items
+	}
+
+	return schema, nil
+}
+
+//ValidateJSON check JSON document against schema.
+//Returned error is *ValidationError with all found problems.
+func (s *JSONSchema) ValidateJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	var problems []ValidationProblem
+	s.validate("$", value, &problems)
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (s *JSONSchema) validate(path string, value interface{}, problems *[]ValidationProblem) {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.types) > 0 && !s.matchType(value) {
+		report("expected %s, got %s", strings.Join(s.types, " or "), jsonTypeOf(value))
+		return
+	}
+
+	if len(s.enum) > 0 {
+		found := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("value is not one of enum values")
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				report("required property %q is missing", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.properties[name]; ok {
+				prop.validate(path+"."+name, v[name], problems)
+			} else if s.additionalProperties != nil {
+				s.additionalProperties.validate(path+"."+name, v[name], problems)
+			} else if s.noAdditional {
+				report("property %q is not allowed", name)
+			}
+		}
+
+	case []interface{}:
+		if s.minItems != nil && len(v) < *s.minItems {
+			report("expected at least %d items, got %d", *s.minItems, len(v))
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			report("expected at most %d items, got %d", *s.maxItems, len(v))
+		}
+		if s.items != nil {
+			for i, item := range v {
+				s.items.validate(path+"["+strconv.Itoa(i)+"]", item, problems)
+			}
+		}
+
+	case string:
+		length := len([]rune(v))
+		if s.minLength != nil && length < *s.minLength {
+			report("expected at least %d characters, got %d", *s.minLength, length)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			report("expected at most %d characters, got %d", *s.maxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			report("value does not match pattern %q", s.pattern.String())
+		}
+
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			report("value %v is less than minimum %v", v, *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			report("value %v is greater than maximum %v", v, *s.maximum)
+		}
+	}
+}
+
+func (s *JSONSchema) matchType(value interface{}) bool {
+	actual := jsonTypeOf(value)
+	for _, t := range s.types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+//jsonTypeOf return JSON Schema type name of decoded value
+func jsonTypeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,106 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (u *testUser) Validate() error {
+	if u.ID == 0 {
+		return &ValidationError{Problems: []ValidationProblem{{Path: "$.id", Message: "id is required"}}}
+	}
+	if len(u.Name) == 0 {
+		return errors.New("name is empty")
+	}
+	return nil
+}
+
+const testUserSchema = `{
+	"type": "object",
+	"required": ["id", "name"],
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"name": {"type": "string", "minLength": 1},
+		"role": {"enum": ["admin", "user"]},
+		"tags": {"type": "array", "maxItems": 2, "items": {"type": "string", "pattern": "^[a-z]+$"}}
+	},
+	"additionalProperties": false
+}`
+
+func newValidateServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+}
+
+func TestResponseValidator(t *testing.T) {
+	server := newValidateServer(`{"name":"bob"}`)
+	defer server.Close()
+
+	//validation is disabled by default
+	require.Nil(t, NewAPIClient(1000).GetJSON(server.URL, &testUser{}))
+
+	client := NewAPIClient(1000).WithResponseValidation()
+	err := client.GetJSON(server.URL, &testUser{})
+	require.IsType(t, &ValidationError{}, err)
+	assert.Equal(t, server.URL, err.(*ValidationError).URL)
+	assert.Equal(t, []ValidationProblem{{Path: "$.id", Message: "id is required"}}, err.(*ValidationError).Problems)
+
+	server2 := newValidateServer(`{"id":1}`)
+	defer server2.Close()
+	err = client.GetJSON(server2.URL, &testUser{})
+	require.IsType(t, &ValidationError{}, err)
+	assert.Equal(t, []ValidationProblem{{Path: "$", Message: "name is empty"}}, err.(*ValidationError).Problems)
+}
+
+func TestResponseSchema(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(testUserSchema))
+	require.Nil(t, err)
+
+	server := newValidateServer(`{"id":1.5,"role":"root","tags":["a","B","c"],"extra":true}`)
+	defer server.Close()
+
+	ctx := ContextWithResponseSchema(context.Background(), schema)
+	err = NewAPIClient(1000).GetJSONContext(ctx, server.URL, &map[string]interface{}{})
+	require.IsType(t, &ValidationError{}, err)
+	assert.Equal(t, server.URL, err.(*ValidationError).URL)
+	assert.Equal(t, []ValidationProblem{
+		{Path: "$", Message: `required property "name" is missing`},
+		{Path: "$", Message: `property "extra" is not allowed`},
+		{Path: "$.id", Message: "expected integer, got number"},
+		{Path: "$.role", Message: "value is not one of enum values"},
+		{Path: "$.tags", Message: "expected at most 2 items, got 3"},
+		{Path: "$.tags[1]", Message: `value does not match pattern "^[a-z]+$"`},
+	}, err.(*ValidationError).Problems)
+
+	valid := newValidateServer(`{"id":7,"name":"bob","tags":["x"]}`)
+	defer valid.Close()
+	user := &testUser{}
+	require.Nil(t, NewAPIClient(1000).GetJSONContext(ctx, valid.URL, user))
+	assert.Equal(t, &testUser{ID: 7, Name: "bob"}, user)
+}
+
+func TestParseJSONSchemaErrors(t *testing.T) {
+	_, err := ParseJSONSchema([]byte(`{"type":"text"}`))
+	assert.NotNil(t, err)
+	_, err = ParseJSONSchema([]byte(`{"properties":{"a":{"pattern":"("}}}`))
+	assert.NotNil(t, err)
+	_, err = ParseJSONSchema([]byte(`[]`))
+	assert.NotNil(t, err)
+
+	schema, err := ParseJSONSchema([]byte(`{"type":["string","null"],"additionalProperties":{"type":"number"}}`))
+	require.Nil(t, err)
+	assert.Nil(t, schema.ValidateJSON([]byte(`null`)))
+	assert.NotNil(t, schema.ValidateJSON([]byte(`1`)))
+}
@@ -0,0 +1,338 @@
+package util
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	//ErrWebhookSignature returned when webhook signature is missing or invalid
+	ErrWebhookSignature = errors.New("webhook: invalid signature")
+
+	//ErrWebhookTimestamp returned when signed timestamp is missing
+	//or out of tolerance
+	ErrWebhookTimestamp = errors.New("webhook: timestamp out of tolerance")
+
+	//ErrWebhookReplay returned when delivery id was already received
+	ErrWebhookReplay = errors.New("webhook: replayed delivery")
+
+	//ErrWebhookTooLarge returned when webhook body exceeds MaxBodyBytes
+	ErrWebhookTooLarge = errors.New("webhook: body too large")
+)
+
+//DefaultWebhookReplayWindow how long delivery ids are remembered
+//when verifier has no timestamp tolerance
+const DefaultWebhookReplayWindow = 24 * time.Hour
+
+//WebhookScheme verifies signature of webhook request
+type WebhookScheme interface {
+	//Verify check request signature of body.
+	//Return signed timestamp or zero time when scheme has no timestamp.
+	Verify(r *http.Request, body, secret []byte) (time.Time, error)
+}
+
+//HMACWebhookScheme is hex HMAC-SHA256 signature in request header.
+//When TimestampHeader is set, signed payload is "<timestamp>.<body>"
+//and timestamp is unix seconds value of that header.
+type HMACWebhookScheme struct {
+	//SignatureHeader header with signature
+	SignatureHeader string
+
+	//Prefix of header value before hex signature, e.g. "sha256="
+	Prefix string
+
+	//TimestampHeader optional header with signed unix timestamp
+	TimestampHeader string
+}
+
+//GitHubWebhookScheme verifies X-Hub-Signature-256 header
+var GitHubWebhookScheme = &HMACWebhookScheme{
+	SignatureHeader: "X-Hub-Signature-256",
+	Prefix:          "sha256=",
+}
+
+//Verify implements WebhookScheme
+func (s *HMACWebhookScheme) Verify(r *http.Request, body, secret []byte) (time.Time, error) {
+	value := r.Header.Get(s.SignatureHeader)
+	if !strings.HasPrefix(value, s.Prefix) {
+		return time.Time{}, ErrWebhookSignature
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(value, s.Prefix))
+	if err != nil {
+		return time.Time{}, ErrWebhookSignature
+	}
+
+	if len(s.TimestampHeader) == 0 {
+		if !checkHMAC(secret, signature, body) {
+			return time.Time{}, ErrWebhookSignature
+		}
+		return time.Time{}, nil
+	}
+
+	rawTimestamp := r.Header.Get(s.TimestampHeader)
+	timestamp, err := parseUnixTimestamp(rawTimestamp)
+	if err != nil {
+		return time.Time{}, ErrWebhookTimestamp
+	}
+	if !checkHMAC(secret, signature, []byte(rawTimestamp+"."), body) {
+		return time.Time{}, ErrWebhookSignature
+	}
+	return timestamp, nil
+}
+
+//StripeWebhookScheme verifies Stripe-Signature header
+//in format "t=<timestamp>,v1=<signature>[,v1=<signature>]"
+var StripeWebhookScheme WebhookScheme = stripeWebhookScheme{}
+
+type stripeWebhookScheme struct{}
+
+func (stripeWebhookScheme) Verify(r *http.Request, body, secret []byte) (time.Time, error) {
+	var rawTimestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(r.Header.Get("Stripe-Signature"), ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			rawTimestamp = kv[1]
+		case "v1":
+			if signature, err := hex.DecodeString(kv[1]); err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+
+	timestamp, err := parseUnixTimestamp(rawTimestamp)
+	if err != nil {
+		return time.Time{}, ErrWebhookTimestamp
+	}
+	for _, signature := range signatures {
+		if checkHMAC(secret, signature, []byte(rawTimestamp+"."), body) {
+			return timestamp, nil
+		}
+	}
+	return time.Time{}, ErrWebhookSignature
+}
+
+//checkHMAC compare signature with HMAC-SHA256 of parts in constant time
+func checkHMAC(secret, signature []byte, parts ...[]byte) bool {
+	mac := hmac.New(sha256.New, secret)
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+func parseUnixTimestamp(value string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+//ReplayStore remembers received webhook delivery ids.
+//Implementation must be safe for concurrent use.
+type ReplayStore interface {
+	//Seen return true when id was already stored,
+	//otherwise stores id until expiresAt and return false
+	Seen(id string, expiresAt time.Time) bool
+
+	//Forget remove stored id, so delivery with it is accepted again
+	Forget(id string)
+}
+
+//MemoryReplayStore keeps delivery ids in memory
+type MemoryReplayStore struct {
+	mu  sync.Mutex
+	ids map[string]time.Time
+}
+
+//NewMemoryReplayStore create in-memory replay store
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{ids: make(map[string]time.Time)}
+}
+
+//Seen implements ReplayStore
+func (s *MemoryReplayStore) Seen(id string, expiresAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if exp, ok := s.ids[id]; ok && now.Before(exp) {
+		return true
+	}
+	for k, exp := range s.ids {
+		if !now.Before(exp) {
+			delete(s.ids, k)
+		}
+	}
+	s.ids[id] = expiresAt
+	return false
+}
+
+//Forget implements ReplayStore
+func (s *MemoryReplayStore) Forget(id string) {
+	s.mu.Lock()
+	delete(s.ids, id)
+	s.mu.Unlock()
+}
+
+//WebhookVerifier checks signature, timestamp and delivery id
+//of incoming webhook requests
+type WebhookVerifier struct {
+	//Secret shared with webhook sender
+	Secret []byte
+
+	//Scheme of signature, e.g. GitHubWebhookScheme or StripeWebhookScheme
+	Scheme WebhookScheme
+
+	//Tolerance max difference between signed timestamp and current time.
+	//Zero disables timestamp check.
+	Tolerance time.Duration
+
+	//DeliveryIDHeader header with unique delivery id, e.g. X-GitHub-Delivery.
+	//Replay check is enabled when both header and Replays are set,
+	//then requests without delivery id are rejected as replays.
+	DeliveryIDHeader string
+	Replays          ReplayStore
+
+	//MaxBodyBytes limits webhook body size, unlimited when zero
+	MaxBodyBytes int64
+}
+
+//Verify check webhook request and stores JSON payload
+//in the value pointed to by payload, payload may be nil.
+//Delivery id of verified request is stored in Replays, when processing
+//of webhook fails Forget must be called, so redelivery is accepted.
+func (v *WebhookVerifier) Verify(r *http.Request, payload interface{}) error {
+	body, err := v.readBody(r)
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := v.Scheme.Verify(r, body, v.Secret)
+	if err != nil {
+		return err
+	}
+	if v.Tolerance > 0 {
+		if timestamp.IsZero() {
+			return ErrWebhookTimestamp
+		}
+		diff := time.Since(timestamp)
+		if diff > v.Tolerance || diff < -v.Tolerance {
+			return ErrWebhookTimestamp
+		}
+	}
+
+	if payload != nil {
+		if err := json.Unmarshal(body, payload); err != nil {
+			return err
+		}
+	}
+
+	if len(v.DeliveryIDHeader) > 0 && v.Replays != nil {
+		id := r.Header.Get(v.DeliveryIDHeader)
+		if len(id) == 0 {
+			return ErrWebhookReplay
+		}
+		window := DefaultWebhookReplayWindow
+		if v.Tolerance > 0 {
+			window = 2 * v.Tolerance
+		}
+		if v.Replays.Seen(id, time.Now().Add(window)) {
+			return ErrWebhookReplay
+		}
+	}
+	return nil
+}
+
+//Forget remove delivery id of request from Replays
+//after failed processing of verified webhook
+func (v *WebhookVerifier) Forget(r *http.Request) {
+	if len(v.DeliveryIDHeader) == 0 || v.Replays == nil {
+		return
+	}
+	if id := r.Header.Get(v.DeliveryIDHeader); len(id) > 0 {
+		v.Replays.Forget(id)
+	}
+}
+
+func (v *WebhookVerifier) readBody(r *http.Request) ([]byte, error) {
+	reader := io.Reader(r.Body)
+	if v.MaxBodyBytes > 0 {
+		reader = &limitedBody{r: r.Body, left: v.MaxBodyBytes, err: ErrWebhookTooLarge}
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	//restore body for handler
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+//Handler return http.Handler which verifies webhook, decodes payload
+//created by newPayload and pass it to handle.
+//Rejected webhooks get 401, 409, 413 or 400 status.
+//When handle responds with not 2xx status delivery id is forgotten,
+//so sender can redeliver webhook.
+func (v *WebhookVerifier) Handler(newPayload func() interface{},
+	handle func(w http.ResponseWriter, r *http.Request, payload interface{})) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload interface{}
+		if newPayload != nil {
+			payload = newPayload()
+		}
+
+		switch err := v.Verify(r, payload); err {
+		case nil:
+			rw := &statusResponseWriter{ResponseWriter: w}
+			handle(rw, r, payload)
+			if rw.status != 0 && (rw.status < 200 || rw.status >= 300) {
+				v.Forget(r)
+			}
+		case ErrWebhookSignature, ErrWebhookTimestamp:
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case ErrWebhookReplay:
+			http.Error(w, err.Error(), http.StatusConflict)
+		case ErrWebhookTooLarge:
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	})
+}
+
+//statusResponseWriter remembers response status code
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
@@ -0,0 +1,149 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testWebhookEvent struct {
+	Type string `json:"type"`
+}
+
+func testHMAC(secret string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookVerifierGitHub(t *testing.T) {
+	verifier := &WebhookVerifier{
+		Secret:           []byte("secret"),
+		Scheme:           GitHubWebhookScheme,
+		DeliveryIDHeader: "X-GitHub-Delivery",
+		Replays:          NewMemoryReplayStore(),
+	}
+
+	var received []string
+	handler := verifier.Handler(
+		func() interface{} { return &testWebhookEvent{} },
+		func(w http.ResponseWriter, r *http.Request, payload interface{}) {
+			received = append(received, payload.(*testWebhookEvent).Type)
+		})
+
+	send := func(body, signature, delivery string) int {
+		req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature-256", signature)
+		req.Header.Set("X-GitHub-Delivery", delivery)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	body := `{"type":"push"}`
+	signature := "sha256=" + testHMAC("secret", body)
+
+	assert.Equal(t, http.StatusOK, send(body, signature, "1"))
+	assert.Equal(t, http.StatusConflict, send(body, signature, "1"))
+	assert.Equal(t, http.StatusOK, send(body, signature, "2"))
+	assert.Equal(t, http.StatusUnauthorized, send(body, "sha256="+testHMAC("other", body), "3"))
+	assert.Equal(t, http.StatusUnauthorized, send(body, testHMAC("secret", body), "4"))
+	assert.Equal(t, http.StatusUnauthorized, send(`{"type":"pull"}`, signature, "5"))
+
+	invalid := "not json"
+	assert.Equal(t, http.StatusBadRequest, send(invalid, "sha256="+testHMAC("secret", invalid), "6"))
+
+	assert.Equal(t, []string{"push", "push"}, received)
+}
+
+func TestWebhookVerifierFailedHandler(t *testing.T) {
+	verifier := &WebhookVerifier{
+		Secret:           []byte("secret"),
+		Scheme:           GitHubWebhookScheme,
+		DeliveryIDHeader: "X-GitHub-Delivery",
+		Replays:          NewMemoryReplayStore(),
+	}
+
+	calls := 0
+	handler := verifier.Handler(nil, func(w http.ResponseWriter, r *http.Request, payload interface{}) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "database is down", http.StatusServiceUnavailable)
+		}
+	})
+
+	body := `{"type":"push"}`
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature-256", "sha256="+testHMAC("secret", body))
+		req.Header.Set("X-GitHub-Delivery", "1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	//redelivery of failed webhook is processed
+	assert.Equal(t, http.StatusServiceUnavailable, send())
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusConflict, send())
+	assert.Equal(t, 2, calls)
+}
+
+func TestWebhookVerifierStripe(t *testing.T) {
+	verifier := &WebhookVerifier{
+		Secret:    []byte("whsec"),
+		Scheme:    StripeWebhookScheme,
+		Tolerance: 5 * time.Minute,
+	}
+
+	body := `{"type":"charge.succeeded"}`
+	verify := func(timestamp time.Time, secret string) error {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+		req.Header.Set("Stripe-Signature",
+			"t="+ts+",v1="+testHMAC("rotated", ts+"."+body)+",v1="+testHMAC(secret, ts+"."+body))
+		event := &testWebhookEvent{}
+		err := verifier.Verify(req, event)
+		if err == nil {
+			assert.Equal(t, "charge.succeeded", event.Type)
+		}
+		return err
+	}
+
+	require.Nil(t, verify(time.Now(), "whsec"))
+	assert.Equal(t, ErrWebhookSignature, verify(time.Now(), "other"))
+	assert.Equal(t, ErrWebhookTimestamp, verify(time.Now().Add(-10*time.Minute), "whsec"))
+	assert.Equal(t, ErrWebhookTimestamp, verify(time.Now().Add(10*time.Minute), "whsec"))
+}
+
+func TestWebhookVerifierHMACWithTimestamp(t *testing.T) {
+	verifier := &WebhookVerifier{
+		Secret: []byte("secret"),
+		Scheme: &HMACWebhookScheme{
+			SignatureHeader: "X-Signature",
+			TimestampHeader: "X-Timestamp",
+		},
+		Tolerance:    time.Minute,
+		MaxBodyBytes: 32,
+	}
+
+	verify := func(body string) error {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+		req.Header.Set("X-Timestamp", ts)
+		req.Header.Set("X-Signature", testHMAC("secret", ts+"."+body))
+		return verifier.Verify(req, nil)
+	}
+
+	require.Nil(t, verify(`{"type":"ok"}`))
+	assert.Equal(t, ErrWebhookTooLarge, verify(`{"type":"`+strings.Repeat("x", 32)+`"}`))
+}